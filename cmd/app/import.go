@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"url_shortener/internal/app"
+	"url_shortener/internal/model"
+	"url_shortener/internal/service"
+	"url_shortener/internal/utils/linkfile"
+
+	"github.com/eerzho/simpledi"
+)
+
+// runImport implements "import [-format csv|jsonl] <file|->": it imports
+// the links of a file as the admin, so owners in the file are kept, and
+// prints the rows that failed. It returns the exit code.
+func runImport(logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", linkfile.FormatCSV, "file format: csv, jsonl")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: import [-format csv|jsonl] <file|->")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var input io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			logger.Error("failed to open import file", slog.Any("error", err))
+			return 1
+		}
+		defer file.Close()
+		input = file
+	}
+
+	app.Setup(logger)
+	defer app.Reset(logger)
+
+	urlService := simpledi.MustGetAs[*service.URL]("urlService")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx = model.WithPrincipal(ctx, &model.Principal{Admin: true})
+
+	report, err := urlService.Import(ctx, input, *format)
+	if err != nil {
+		logger.Error("import failed", slog.Any("error", err))
+		return 1
+	}
+
+	for _, failure := range report.Failed {
+		message := failure.Err.Error()
+		var modelErr *model.Error
+		if errors.As(failure.Err, &modelErr) {
+			message = modelErr.Error()
+		}
+		fmt.Printf("line %d\t%s\t%s\n", failure.Line, failure.ShortCode, message)
+	}
+	fmt.Printf("imported %d, failed %d\n", report.Imported, len(report.Failed))
+
+	if len(report.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
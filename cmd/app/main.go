@@ -3,34 +3,120 @@ package main
 import (
 	"context"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 	_ "url_shortener/docs"
 	"url_shortener/internal/app"
 	"url_shortener/internal/config"
 	"url_shortener/internal/handler"
+	"url_shortener/internal/handler/rpc"
+	"url_shortener/internal/service"
 	utilslogger "url_shortener/internal/utils/logger"
+	"url_shortener/internal/worker"
 
 	"github.com/eerzho/simpledi"
 	swagger "github.com/swaggo/http-swagger"
+	"google.golang.org/grpc"
 )
 
 // main godoc
 //
-//	@Title		url shortener api
-//	@Version	1.0
-//	@BasePath	/
+//	@Title						url shortener api
+//	@Version					1.0
+//	@BasePath					/
+//	@SecurityDefinitions.apikey	BearerAuth
+//	@In							header
+//	@Name						Authorization
+//	@Description				API key sent as "Bearer <key>".
 func main() {
 	logger := utilslogger.NewLogger(os.Getenv("APP_ENV"))
 
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(logger, os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "normalize" {
+		os.Exit(runNormalize(logger, os.Args[2:]))
+	}
+
 	app.Setup(logger)
 	defer app.Reset(logger)
 
+	reconcileCounter(logger)
+	startWorkers()
+
 	server := setupServer()
+	grpcServer := rpc.NewServer()
 	startServer(logger, server)
+	startGRPCServer(logger, grpcServer)
 	stopServer(logger, server)
+	stopGRPCServer(logger, grpcServer)
+
+	stopWorkers(logger)
+}
+
+// reconcileCounter moves the short code counter past every value stored in
+// Postgres, in case Valkey lost it. A failure is not fatal: creates that hit
+// a taken code reconcile again.
+func reconcileCounter(logger *slog.Logger) {
+	cfg := simpledi.MustGetAs[*config.Config]("config")
+	counterGenerator := simpledi.MustGetAs[*service.CounterGenerator]("counterGenerator")
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.RequestTimeout)
+	defer cancel()
+
+	if err := counterGenerator.Reconcile(ctx); err != nil {
+		logger.Error("failed to reconcile counter", slog.Any("error", err))
+	}
+}
+
+func startWorkers() {
+	reaper := simpledi.MustGetAs[*worker.Reaper]("urlReaper")
+	reaper.Start()
+
+	clickPool := simpledi.MustGetAs[*worker.ClickPool]("clickPool")
+	clickPool.Start()
+
+	blocklistReloader := simpledi.MustGetAs[*worker.BlocklistReloader]("blocklistReloader")
+	blocklistReloader.Start()
+
+	cacheInvalidator := simpledi.MustGetAs[*worker.CacheInvalidator]("cacheInvalidator")
+	cacheInvalidator.Start()
+
+	filterBuilder := simpledi.MustGetAs[*worker.FilterBuilder]("filterBuilder")
+	filterBuilder.Start()
+
+	cacheWarmer := simpledi.MustGetAs[*worker.CacheWarmer]("cacheWarmer")
+	cacheWarmer.Start()
+}
+
+// stopWorkers runs after the http server has stopped accepting requests and
+// before app.Reset closes the connections the workers write through.
+func stopWorkers(logger *slog.Logger) {
+	logger.Info("stopping workers...")
+
+	reaper := simpledi.MustGetAs[*worker.Reaper]("urlReaper")
+	reaper.Stop()
+
+	clickPool := simpledi.MustGetAs[*worker.ClickPool]("clickPool")
+	clickPool.Stop()
+
+	blocklistReloader := simpledi.MustGetAs[*worker.BlocklistReloader]("blocklistReloader")
+	blocklistReloader.Stop()
+
+	cacheInvalidator := simpledi.MustGetAs[*worker.CacheInvalidator]("cacheInvalidator")
+	cacheInvalidator.Stop()
+
+	filterBuilder := simpledi.MustGetAs[*worker.FilterBuilder]("filterBuilder")
+	filterBuilder.Stop()
+
+	cacheWarmer := simpledi.MustGetAs[*worker.CacheWarmer]("cacheWarmer")
+	cacheWarmer.Stop()
+
+	logger.Info("workers stopped")
 }
 
 func setupServer() *http.Server {
@@ -79,3 +165,42 @@ func stopServer(logger *slog.Logger, server *http.Server) {
 
 	logger.Info("http server exited")
 }
+
+func startGRPCServer(logger *slog.Logger, server *grpc.Server) {
+	cfg := simpledi.MustGetAs[*config.Config]("config")
+
+	go func() {
+		logger.Info("starting grpc server", slog.String("port", cfg.GRPC.Addr))
+		listener, err := net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			logger.Error("grpc server failed", slog.Any("error", err))
+			return
+		}
+		if err := server.Serve(listener); err != nil {
+			logger.Error("grpc server failed", slog.Any("error", err))
+			return
+		}
+	}()
+}
+
+// stopGRPCServer lets in-flight rpcs finish and cancels the ones still
+// running once GRPC_SHUTDOWN_TIMEOUT has passed.
+func stopGRPCServer(logger *slog.Logger, server *grpc.Server) {
+	cfg := simpledi.MustGetAs[*config.Config]("config")
+
+	logger.Info("shutting down grpc server...")
+
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("grpc server exited")
+	case <-time.After(cfg.GRPC.ShutdownTimeout):
+		server.Stop()
+		logger.Error("grpc server forced to shutdown")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"url_shortener/internal/app"
+	"url_shortener/internal/service"
+
+	"github.com/eerzho/simpledi"
+)
+
+// runNormalize fills in the canonical url of the links migration 000010
+// copied the original url into, once after upgrading.
+func runNormalize(logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("normalize", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: normalize")
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	app.Setup(logger)
+	defer app.Reset(logger)
+
+	canonicalizer := simpledi.MustGetAs[*service.Canonicalizer]("canonicalizer")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	updated, skipped, err := canonicalizer.Run(ctx)
+	fmt.Printf("updated %d, skipped %d\n", updated, skipped)
+	if err != nil {
+		logger.Error("normalize failed", slog.Any("error", err))
+		return 1
+	}
+	return 0
+}
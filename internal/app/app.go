@@ -2,18 +2,29 @@ package app
 
 import (
 	"log/slog"
+	"net"
 	"time"
 	"url_shortener/internal/config"
 	"url_shortener/internal/handler"
 	"url_shortener/internal/handler/middleware"
+	"url_shortener/internal/handler/rpc"
+	"url_shortener/internal/repository"
+	fileRepo "url_shortener/internal/repository/file"
+	memoryRepo "url_shortener/internal/repository/memory"
 	postgresRepo "url_shortener/internal/repository/postgres"
 	valkeyRepo "url_shortener/internal/repository/valkey"
 	"url_shortener/internal/service"
+	"url_shortener/internal/utils/breaker"
 	postgresUtils "url_shortener/internal/utils/postgres"
+	"url_shortener/internal/utils/shortcode"
+	"url_shortener/internal/utils/ssrf"
+	"url_shortener/internal/utils/urlnorm"
 	validateUtils "url_shortener/internal/utils/validate"
 	valkeyUtils "url_shortener/internal/utils/valkey"
+	"url_shortener/internal/worker"
 
 	"github.com/eerzho/simpledi"
+	"github.com/go-playground/validator/v10"
 	"github.com/jmoiron/sqlx"
 	valkeygo "github.com/valkey-io/valkey-go"
 )
@@ -105,15 +116,75 @@ func defs() []simpledi.Def {
 				)
 			},
 		},
+		{
+			Key:  "counterPostgresRepo",
+			Deps: []string{"postgres"},
+			Ctor: func() any {
+				db := simpledi.MustGetAs[*sqlx.DB]("postgres")
+				return postgresRepo.NewCounter(
+					db,
+				)
+			},
+		},
+		{
+			Key:  "counterMemoryRepo",
+			Deps: []string{"config", "logger", "counterValkeyRepo", "counterPostgresRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				var counterRepo repository.CounterRange = simpledi.MustGetAs[*valkeyRepo.Counter]("counterValkeyRepo")
+				if cfg.Counter.Backend == "postgres" {
+					counterRepo = simpledi.MustGetAs[*postgresRepo.Counter]("counterPostgresRepo")
+				}
+				return memoryRepo.NewCounter(
+					cfg.Counter.BlockSize,
+					logger,
+					counterRepo,
+				)
+			},
+		},
+		{
+			Key:  "urlBreakerRepo",
+			Deps: []string{"config", "urlPostgresRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				urlRepo := simpledi.MustGetAs[*postgresRepo.URL]("urlPostgresRepo")
+				return memoryRepo.NewURLBreaker(
+					breaker.New(
+						cfg.Postgres.BreakerThreshold,
+						cfg.Postgres.BreakerCooldown,
+					),
+					urlRepo,
+				)
+			},
+		},
+		{
+			Key:  "urlFilterRepo",
+			Deps: []string{"config", "urlBreakerRepo", "urlPostgresRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				urlRepo := simpledi.MustGetAs[*memoryRepo.URLBreaker]("urlBreakerRepo")
+				shortCodeSource := simpledi.MustGetAs[*postgresRepo.URL]("urlPostgresRepo")
+				return memoryRepo.NewShortCodeFilter(
+					cfg.Cache.FilterCapacity,
+					cfg.Cache.FilterFalsePositiveRate,
+					urlRepo,
+					shortCodeSource,
+				)
+			},
+		},
 		{
 			Key:  "urlValkeyRepo",
-			Deps: []string{"logger", "valkey", "urlPostgresRepo"},
+			Deps: []string{"config", "logger", "valkey", "urlFilterRepo"},
 			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
 				logger := simpledi.MustGetAs[*slog.Logger]("logger")
 				client := simpledi.MustGetAs[valkeygo.Client]("valkey")
-				urlRepo := simpledi.MustGetAs[*postgresRepo.URL]("urlPostgresRepo")
+				urlRepo := simpledi.MustGetAs[*memoryRepo.ShortCodeFilter]("urlFilterRepo")
 				return valkeyRepo.NewURL(
 					time.Hour*24,
+					cfg.Cache.SoftTTL,
+					cfg.Cache.NotFoundTTL,
 					logger,
 					client,
 					urlRepo,
@@ -121,14 +192,273 @@ func defs() []simpledi.Def {
 			},
 		},
 		{
-			Key:  "urlService",
-			Deps: []string{"urlValkeyRepo", "counterValkeyRepo"},
+			Key:  "urlMemoryRepo",
+			Deps: []string{"config", "urlValkeyRepo"},
 			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
 				urlRepo := simpledi.MustGetAs[*valkeyRepo.URL]("urlValkeyRepo")
-				counterRepo := simpledi.MustGetAs[*valkeyRepo.Counter]("counterValkeyRepo")
-				return service.NewURL(
+				return memoryRepo.NewURL(
+					cfg.Cache.LocalSize,
+					cfg.Cache.LocalTTL,
+					urlRepo,
+				)
+			},
+		},
+		{
+			Key:  "invalidationValkeyRepo",
+			Deps: []string{"valkey"},
+			Ctor: func() any {
+				client := simpledi.MustGetAs[valkeygo.Client]("valkey")
+				return valkeyRepo.NewInvalidation(
+					client,
+				)
+			},
+		},
+		{
+			Key:  "cacheInvalidator",
+			Deps: []string{"logger", "invalidationValkeyRepo", "urlMemoryRepo", "urlFilterRepo"},
+			Ctor: func() any {
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				invalidationRepo := simpledi.MustGetAs[*valkeyRepo.Invalidation]("invalidationValkeyRepo")
+				urlRepo := simpledi.MustGetAs[*memoryRepo.URL]("urlMemoryRepo")
+				urlFilterRepo := simpledi.MustGetAs[*memoryRepo.ShortCodeFilter]("urlFilterRepo")
+				return worker.NewCacheInvalidator(
+					logger,
+					invalidationRepo,
+					[]worker.URLCache{urlRepo, urlFilterRepo},
+				)
+			},
+		},
+		{
+			Key:  "cacheWarmer",
+			Deps: []string{"config", "logger", "urlPostgresRepo", "urlValkeyRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				urlRepo := simpledi.MustGetAs[*postgresRepo.URL]("urlPostgresRepo")
+				urlValkeyRepo := simpledi.MustGetAs[*valkeyRepo.URL]("urlValkeyRepo")
+				return worker.NewCacheWarmer(
+					cfg.Cache.WarmSize,
+					cfg.Cache.WarmBy,
+					cfg.Cache.WarmWindow,
+					logger,
 					urlRepo,
+					urlValkeyRepo,
+				)
+			},
+		},
+		{
+			Key:  "filterBuilder",
+			Deps: []string{"config", "logger", "urlFilterRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				urlFilterRepo := simpledi.MustGetAs[*memoryRepo.ShortCodeFilter]("urlFilterRepo")
+				return worker.NewFilterBuilder(
+					cfg.Cache.FilterRebuildInterval,
+					logger,
+					urlFilterRepo,
+				)
+			},
+		},
+		{
+			Key:  "counterGenerator",
+			Deps: []string{"config", "counterMemoryRepo", "urlPostgresRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				counterRepo := simpledi.MustGetAs[*memoryRepo.Counter]("counterMemoryRepo")
+				urlRepo := simpledi.MustGetAs[*postgresRepo.URL]("urlPostgresRepo")
+				return service.NewCounterGenerator(
+					shortcode.New(
+						cfg.ShortCode.Key,
+						cfg.ShortCode.MinLength,
+					),
 					counterRepo,
+					urlRepo,
+				)
+			},
+		},
+		{
+			Key:  "blocklistFileRepo",
+			Deps: []string{"config"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				return fileRepo.MustNewBlocklist(
+					cfg.Policy.BlocklistFile,
+				)
+			},
+		},
+		{
+			Key:  "destinationValidator",
+			Deps: []string{"config"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				return ssrf.MustNew(
+					net.DefaultResolver,
+					cfg.Policy.AllowedAddresses,
+				)
+			},
+		},
+		{
+			Key:  "urlPolicy",
+			Deps: []string{"config", "blocklistFileRepo", "destinationValidator"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				blocklistRepo := simpledi.MustGetAs[*fileRepo.Blocklist]("blocklistFileRepo")
+				destinationValidator := simpledi.MustGetAs[*ssrf.Validator]("destinationValidator")
+				rules := []service.PolicyRule{
+					service.NewSelfDomainRule(cfg.Policy.SelfDomains),
+					service.NewBlocklistRule(blocklistRepo),
+				}
+				if cfg.Policy.ResolveDestinations {
+					rules = append(rules, service.NewDestinationRule(
+						cfg.Policy.ResolveTimeout,
+						destinationValidator,
+					))
+				}
+				return service.NewPolicy(rules...)
+			},
+		},
+		{
+			Key:  "urlNormalizer",
+			Deps: []string{"config"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				return urlnorm.New(
+					cfg.URL.Schemes,
+					cfg.URL.StripTracking,
+				)
+			},
+		},
+		{
+			Key:  "urlService",
+			Deps: []string{"config", "urlMemoryRepo", "counterGenerator", "urlNormalizer", "urlPolicy"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				urlRepo := simpledi.MustGetAs[*memoryRepo.URL]("urlMemoryRepo")
+				counterGenerator := simpledi.MustGetAs[*service.CounterGenerator]("counterGenerator")
+				urlNormalizer := simpledi.MustGetAs[*urlnorm.Normalizer]("urlNormalizer")
+				urlPolicy := simpledi.MustGetAs[*service.Policy]("urlPolicy")
+				return service.NewURL(
+					cfg.Auth.AnonymousCreate,
+					cfg.ShortCode.Strategy,
+					cfg.ShortCode.RequestStrategies,
+					cfg.URL.BatchMaxSize,
+					map[string]service.ShortCodeGenerator{
+						service.StrategyCounter: counterGenerator,
+						service.StrategyRandom:  service.NewRandomGenerator(cfg.ShortCode.Length),
+						service.StrategyHash:    service.NewHashGenerator(cfg.ShortCode.Length),
+						service.StrategyWords:   service.NewWordsGenerator(cfg.ShortCode.Words),
+					},
+					urlNormalizer,
+					urlPolicy,
+					urlRepo,
+				)
+			},
+		},
+		{
+			Key:  "canonicalizer",
+			Deps: []string{"urlNormalizer", "urlPostgresRepo"},
+			Ctor: func() any {
+				urlNormalizer := simpledi.MustGetAs[*urlnorm.Normalizer]("urlNormalizer")
+				urlRepo := simpledi.MustGetAs[*postgresRepo.URL]("urlPostgresRepo")
+				return service.NewCanonicalizer(
+					urlNormalizer,
+					urlRepo,
+				)
+			},
+		},
+		{
+			Key:  "apiKeyPostgresRepo",
+			Deps: []string{"postgres"},
+			Ctor: func() any {
+				db := simpledi.MustGetAs[*sqlx.DB]("postgres")
+				return postgresRepo.NewAPIKey(
+					db,
+				)
+			},
+		},
+		{
+			Key:  "apiKeyService",
+			Deps: []string{"config", "apiKeyPostgresRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				apiKeyRepo := simpledi.MustGetAs[*postgresRepo.APIKey]("apiKeyPostgresRepo")
+				return service.NewAPIKey(
+					cfg.Auth.AdminKey,
+					apiKeyRepo,
+				)
+			},
+		},
+		{
+			Key:  "urlReaper",
+			Deps: []string{"config", "logger", "urlPostgresRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				urlRepo := simpledi.MustGetAs[*postgresRepo.URL]("urlPostgresRepo")
+				return worker.NewReaper(
+					cfg.Reaper.Interval,
+					cfg.Reaper.GracePeriod,
+					cfg.Reaper.BatchSize,
+					logger,
+					urlRepo,
+				)
+			},
+		},
+		{
+			Key:  "blocklistReloader",
+			Deps: []string{"config", "logger", "blocklistFileRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				blocklistRepo := simpledi.MustGetAs[*fileRepo.Blocklist]("blocklistFileRepo")
+				return worker.NewBlocklistReloader(
+					cfg.Policy.BlocklistReloadInterval,
+					logger,
+					blocklistRepo,
+				)
+			},
+		},
+		{
+			Key:  "clickPostgresRepo",
+			Deps: []string{"postgres"},
+			Ctor: func() any {
+				db := simpledi.MustGetAs[*sqlx.DB]("postgres")
+				return postgresRepo.NewClick(
+					db,
+				)
+			},
+		},
+		{
+			Key:  "clickPool",
+			Deps: []string{"config", "logger", "clickPostgresRepo"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				clickRepo := simpledi.MustGetAs[*postgresRepo.Click]("clickPostgresRepo")
+				return worker.NewClickPool(
+					cfg.Clicks.QueueSize,
+					cfg.Clicks.Workers,
+					cfg.Clicks.BatchSize,
+					cfg.Clicks.FlushInterval,
+					cfg.Clicks.Overflow,
+					logger,
+					clickRepo,
+				)
+			},
+		},
+		{
+			Key:  "clickService",
+			Deps: []string{"clickPool", "clickPostgresRepo", "urlMemoryRepo"},
+			Ctor: func() any {
+				clickPool := simpledi.MustGetAs[*worker.ClickPool]("clickPool")
+				clickRepo := simpledi.MustGetAs[*postgresRepo.Click]("clickPostgresRepo")
+				urlRepo := simpledi.MustGetAs[*memoryRepo.URL]("urlMemoryRepo")
+				return service.NewClick(
+					clickPool,
+					clickRepo,
+					urlRepo,
 				)
 			},
 		},
@@ -142,15 +472,181 @@ func defs() []simpledi.Def {
 				)
 			},
 		},
+		{
+			Key:  "authMiddleware",
+			Deps: []string{"apiKeyService"},
+			Ctor: func() any {
+				apiKeyService := simpledi.MustGetAs[*service.APIKey]("apiKeyService")
+				return middleware.NewAuth(
+					apiKeyService,
+				)
+			},
+		},
+		{
+			Key:  "idempotencyValkeyRepo",
+			Deps: []string{"config", "valkey"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				client := simpledi.MustGetAs[valkeygo.Client]("valkey")
+				return valkeyRepo.NewIdempotency(
+					cfg.Idempotency.TTL,
+					cfg.Idempotency.LockTTL,
+					client,
+				)
+			},
+		},
+		{
+			Key:  "idempotencyMiddleware",
+			Deps: []string{"logger", "idempotencyValkeyRepo"},
+			Ctor: func() any {
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				idempotencyRepo := simpledi.MustGetAs[*valkeyRepo.Idempotency]("idempotencyValkeyRepo")
+				return middleware.NewIdempotency(
+					logger,
+					idempotencyRepo,
+				)
+			},
+		},
+		{
+			Key:  "rateLimitBreaker",
+			Deps: []string{"config"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				return breaker.New(
+					cfg.RateLimit.BreakerThreshold,
+					cfg.RateLimit.BreakerCooldown,
+				)
+			},
+		},
+		{
+			Key:  "createRateLimiter",
+			Deps: []string{"config", "logger", "valkey", "rateLimitBreaker"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				return newRateLimiter(
+					"create",
+					cfg.RateLimit.CreateLimit,
+					cfg.RateLimit.CreatePeriod,
+				)
+			},
+		},
+		{
+			Key:  "redirectRateLimiter",
+			Deps: []string{"config", "logger", "valkey", "rateLimitBreaker"},
+			Ctor: func() any {
+				cfg := simpledi.MustGetAs[*config.Config]("config")
+				return newRateLimiter(
+					"redirect",
+					cfg.RateLimit.RedirectLimit,
+					cfg.RateLimit.RedirectPeriod,
+				)
+			},
+		},
+		{
+			Key:  "createRateLimitMiddleware",
+			Deps: []string{"logger", "createRateLimiter"},
+			Ctor: func() any {
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				limiter := simpledi.MustGetAs[middleware.Limiter]("createRateLimiter")
+				return middleware.NewRateLimit(
+					logger,
+					limiter,
+				)
+			},
+		},
+		{
+			Key:  "redirectRateLimitMiddleware",
+			Deps: []string{"logger", "redirectRateLimiter"},
+			Ctor: func() any {
+				logger := simpledi.MustGetAs[*slog.Logger]("logger")
+				limiter := simpledi.MustGetAs[middleware.Limiter]("redirectRateLimiter")
+				return middleware.NewRateLimit(
+					logger,
+					limiter,
+				)
+			},
+		},
 		{
 			Key:  "urlHandler",
-			Deps: []string{"urlService"},
+			Deps: []string{"urlService", "clickService"},
 			Ctor: func() any {
 				urlService := simpledi.MustGetAs[*service.URL]("urlService")
+				clickService := simpledi.MustGetAs[*service.Click]("clickService")
 				return handler.NewURL(
 					urlService,
+					clickService,
+				)
+			},
+		},
+		{
+			Key:  "healthService",
+			Deps: []string{"urlBreakerRepo", "urlMemoryRepo"},
+			Ctor: func() any {
+				urlBreakerRepo := simpledi.MustGetAs[*memoryRepo.URLBreaker]("urlBreakerRepo")
+				urlRepo := simpledi.MustGetAs[*memoryRepo.URL]("urlMemoryRepo")
+				return service.NewHealth(
+					urlBreakerRepo,
+					urlRepo,
+				)
+			},
+		},
+		{
+			Key:  "healthHandler",
+			Deps: []string{"healthService"},
+			Ctor: func() any {
+				healthService := simpledi.MustGetAs[*service.Health]("healthService")
+				return handler.NewHealth(
+					healthService,
+				)
+			},
+		},
+		{
+			Key:  "apiKeyHandler",
+			Deps: []string{"apiKeyService"},
+			Ctor: func() any {
+				apiKeyService := simpledi.MustGetAs[*service.APIKey]("apiKeyService")
+				return handler.NewAPIKey(
+					apiKeyService,
+				)
+			},
+		},
+		{
+			Key:  "urlRPCHandler",
+			Deps: []string{"validate", "urlService"},
+			Ctor: func() any {
+				validate := simpledi.MustGetAs[*validator.Validate]("validate")
+				urlService := simpledi.MustGetAs[*service.URL]("urlService")
+				return rpc.NewURL(
+					validate,
+					urlService,
 				)
 			},
 		},
 	}
 }
+
+func newRateLimiter(name string, limit int, period time.Duration) middleware.Limiter {
+	cfg := simpledi.MustGetAs[*config.Config]("config")
+
+	local := memoryRepo.NewRateLimiter(
+		limit,
+		period,
+		cfg.RateLimit.MaxKeys,
+	)
+	if cfg.RateLimit.Backend != "valkey" {
+		return local
+	}
+
+	logger := simpledi.MustGetAs[*slog.Logger]("logger")
+	client := simpledi.MustGetAs[valkeygo.Client]("valkey")
+	rateLimitBreaker := simpledi.MustGetAs[*breaker.Breaker]("rateLimitBreaker")
+	return valkeyRepo.NewRateLimiter(
+		name,
+		limit,
+		period,
+		logger,
+		client,
+		rateLimitBreaker,
+		local,
+	)
+}
@@ -1,17 +1,46 @@
 package config
 
 import (
+	"fmt"
+	"slices"
 	"time"
+	"url_shortener/internal/utils/shortcode"
 
 	"github.com/caarlos0/env/v11"
 )
 
+const (
+	minShortCodeKeyLength = 16
+	minShortCodeLength    = 4
+	minShortCodeWords     = 2
+	maxShortCodeWords     = 4
+	// maxURLBatchSize keeps a batch insert under the 65535 parameters
+	// Postgres accepts in one statement.
+	maxURLBatchSize = 5000
+	// maxClicksBatchSize keeps the biggest rollup insert of a click batch,
+	// four dimension rows of five parameters per click, under that limit.
+	maxClicksBatchSize = 3000
+)
+
+var shortCodeStrategies = []string{"counter", "random", "hash", "words"}
+
 type (
 	Config struct {
-		APP      APP
-		HTTP     HTTP
-		Postgres Postgres
-		Valkey   Valkey
+		APP         APP
+		HTTP        HTTP
+		GRPC        GRPC
+		Postgres    Postgres
+		Valkey      Valkey
+		Reaper      Reaper
+		Clicks      Clicks
+		RateLimit   RateLimit
+		Auth        Auth
+		ShortCode   ShortCode
+		Counter     Counter
+		Idempotency Idempotency
+		URL         URL
+		Policy      Policy
+		Cache       Cache
 	}
 
 	APP struct {
@@ -25,16 +54,100 @@ type (
 		RequestTimeout time.Duration `env:"HTTP_REQUEST_TIMEOUT" envDefault:"30s"`
 	}
 
+	GRPC struct {
+		Addr            string        `env:"GRPC_ADDR"             envDefault:":9090"`
+		ShutdownTimeout time.Duration `env:"GRPC_SHUTDOWN_TIMEOUT" envDefault:"10s"`
+	}
+
 	Postgres struct {
-		URL             string        `env:"POSTGRES_URL,required"`
-		MaxOpenConns    int           `env:"POSTGRES_MAX_OPEN_CONNS"    envDefault:"25"`
-		MaxIdleConns    int           `env:"POSTGRES_MAX_IDLE_CONNS"    envDefault:"5"`
-		ConnMaxLifetime time.Duration `env:"POSTGRES_CONN_MAX_LIFETIME" envDefault:"5m"`
+		URL              string        `env:"POSTGRES_URL,required"`
+		MaxOpenConns     int           `env:"POSTGRES_MAX_OPEN_CONNS"    envDefault:"25"`
+		MaxIdleConns     int           `env:"POSTGRES_MAX_IDLE_CONNS"    envDefault:"5"`
+		ConnMaxLifetime  time.Duration `env:"POSTGRES_CONN_MAX_LIFETIME" envDefault:"5m"`
+		BreakerThreshold int           `env:"POSTGRES_BREAKER_THRESHOLD" envDefault:"5"`
+		BreakerCooldown  time.Duration `env:"POSTGRES_BREAKER_COOLDOWN"  envDefault:"10s"`
 	}
 
 	Valkey struct {
 		URL string `env:"VALKEY_URL,required"`
 	}
+
+	Reaper struct {
+		Interval    time.Duration `env:"REAPER_INTERVAL"     envDefault:"1m"`
+		GracePeriod time.Duration `env:"REAPER_GRACE_PERIOD" envDefault:"168h"`
+		BatchSize   int           `env:"REAPER_BATCH_SIZE"   envDefault:"1000"`
+	}
+
+	Clicks struct {
+		QueueSize     int           `env:"CLICKS_QUEUE_SIZE"     envDefault:"10000"`
+		Workers       int           `env:"CLICKS_WORKERS"        envDefault:"4"`
+		BatchSize     int           `env:"CLICKS_BATCH_SIZE"     envDefault:"500"`
+		FlushInterval time.Duration `env:"CLICKS_FLUSH_INTERVAL" envDefault:"1s"`
+		Overflow      string        `env:"CLICKS_OVERFLOW"       envDefault:"drop"`
+	}
+
+	RateLimit struct {
+		Backend          string        `env:"RATE_LIMIT_BACKEND"           envDefault:"memory"`
+		MaxKeys          int           `env:"RATE_LIMIT_MAX_KEYS"          envDefault:"100000"`
+		CreateLimit      int           `env:"RATE_LIMIT_CREATE_LIMIT"      envDefault:"10"`
+		CreatePeriod     time.Duration `env:"RATE_LIMIT_CREATE_PERIOD"     envDefault:"1m"`
+		RedirectLimit    int           `env:"RATE_LIMIT_REDIRECT_LIMIT"    envDefault:"100"`
+		RedirectPeriod   time.Duration `env:"RATE_LIMIT_REDIRECT_PERIOD"   envDefault:"1s"`
+		BreakerThreshold int           `env:"RATE_LIMIT_BREAKER_THRESHOLD" envDefault:"5"`
+		BreakerCooldown  time.Duration `env:"RATE_LIMIT_BREAKER_COOLDOWN"  envDefault:"10s"`
+	}
+
+	Auth struct {
+		AdminKey        string `env:"AUTH_ADMIN_KEY"`
+		AnonymousCreate bool   `env:"AUTH_ANONYMOUS_CREATE" envDefault:"false"`
+	}
+
+	ShortCode struct {
+		Strategy          string   `env:"SHORT_CODE_STRATEGY"           envDefault:"counter"`
+		RequestStrategies []string `env:"SHORT_CODE_REQUEST_STRATEGIES" envSeparator:","`
+		Key               string   `env:"SHORT_CODE_KEY"`
+		MinLength         int      `env:"SHORT_CODE_MIN_LENGTH"         envDefault:"6"`
+		Length            int      `env:"SHORT_CODE_LENGTH"             envDefault:"8"`
+		Words             int      `env:"SHORT_CODE_WORDS"              envDefault:"3"`
+	}
+
+	Counter struct {
+		Backend   string `env:"COUNTER_BACKEND"    envDefault:"valkey"`
+		BlockSize int    `env:"COUNTER_BLOCK_SIZE" envDefault:"100"`
+	}
+
+	URL struct {
+		Schemes       []string `env:"URL_SCHEMES"        envDefault:"http,https" envSeparator:","`
+		StripTracking bool     `env:"URL_STRIP_TRACKING" envDefault:"false"`
+		BatchMaxSize  int      `env:"URL_BATCH_MAX_SIZE" envDefault:"1000"`
+	}
+
+	Policy struct {
+		SelfDomains             []string      `env:"POLICY_SELF_DOMAINS"              envSeparator:","`
+		BlocklistFile           string        `env:"POLICY_BLOCKLIST_FILE"`
+		BlocklistReloadInterval time.Duration `env:"POLICY_BLOCKLIST_RELOAD_INTERVAL" envDefault:"30s"`
+		ResolveDestinations     bool          `env:"POLICY_RESOLVE_DESTINATIONS"      envDefault:"false"`
+		ResolveTimeout          time.Duration `env:"POLICY_RESOLVE_TIMEOUT"           envDefault:"2s"`
+		AllowedAddresses        []string      `env:"POLICY_ALLOWED_ADDRESSES"         envSeparator:","`
+	}
+
+	Idempotency struct {
+		TTL     time.Duration `env:"IDEMPOTENCY_TTL"      envDefault:"24h"`
+		LockTTL time.Duration `env:"IDEMPOTENCY_LOCK_TTL" envDefault:"1m"`
+	}
+
+	Cache struct {
+		LocalSize               int           `env:"CACHE_LOCAL_SIZE"                 envDefault:"10000"`
+		LocalTTL                time.Duration `env:"CACHE_LOCAL_TTL"                  envDefault:"5s"`
+		SoftTTL                 time.Duration `env:"CACHE_SOFT_TTL"                   envDefault:"0s"`
+		NotFoundTTL             time.Duration `env:"CACHE_NOT_FOUND_TTL"              envDefault:"30s"`
+		FilterCapacity          int           `env:"CACHE_FILTER_CAPACITY"            envDefault:"0"`
+		FilterFalsePositiveRate float64       `env:"CACHE_FILTER_FALSE_POSITIVE_RATE" envDefault:"0.01"`
+		FilterRebuildInterval   time.Duration `env:"CACHE_FILTER_REBUILD_INTERVAL"    envDefault:"15m"`
+		WarmSize                int           `env:"CACHE_WARM_SIZE"                  envDefault:"1000"`
+		WarmBy                  string        `env:"CACHE_WARM_BY"                    envDefault:"clicks"`
+		WarmWindow              time.Duration `env:"CACHE_WARM_WINDOW"                envDefault:"24h"`
+	}
 )
 
 func NewConfig() (*Config, error) {
@@ -42,6 +155,109 @@ func NewConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.Reaper.Interval <= 0 {
+		return nil, fmt.Errorf("REAPER_INTERVAL must be positive, got %s", cfg.Reaper.Interval)
+	}
+	if cfg.Reaper.BatchSize < 1 {
+		return nil, fmt.Errorf("REAPER_BATCH_SIZE must be positive, got %d", cfg.Reaper.BatchSize)
+	}
+	if cfg.Clicks.Workers < 1 {
+		return nil, fmt.Errorf("CLICKS_WORKERS must be positive, got %d", cfg.Clicks.Workers)
+	}
+	if cfg.Clicks.BatchSize < 1 || cfg.Clicks.BatchSize > maxClicksBatchSize {
+		return nil, fmt.Errorf("CLICKS_BATCH_SIZE must be between 1 and %d, got %d",
+			maxClicksBatchSize, cfg.Clicks.BatchSize)
+	}
+	if cfg.Clicks.FlushInterval <= 0 {
+		return nil, fmt.Errorf("CLICKS_FLUSH_INTERVAL must be positive, got %s", cfg.Clicks.FlushInterval)
+	}
+	if cfg.Clicks.Overflow != "drop" && cfg.Clicks.Overflow != "block" {
+		return nil, fmt.Errorf("CLICKS_OVERFLOW must be drop or block, got %q", cfg.Clicks.Overflow)
+	}
+	if cfg.RateLimit.Backend != "memory" && cfg.RateLimit.Backend != "valkey" {
+		return nil, fmt.Errorf("RATE_LIMIT_BACKEND must be memory or valkey, got %q", cfg.RateLimit.Backend)
+	}
+	if cfg.RateLimit.MaxKeys < 1 {
+		return nil, fmt.Errorf("RATE_LIMIT_MAX_KEYS must be positive, got %d", cfg.RateLimit.MaxKeys)
+	}
+	if cfg.RateLimit.CreateLimit < 1 {
+		return nil, fmt.Errorf("RATE_LIMIT_CREATE_LIMIT must be positive, got %d", cfg.RateLimit.CreateLimit)
+	}
+	if cfg.RateLimit.CreatePeriod <= 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_CREATE_PERIOD must be positive, got %s", cfg.RateLimit.CreatePeriod)
+	}
+	if cfg.RateLimit.RedirectLimit < 1 {
+		return nil, fmt.Errorf("RATE_LIMIT_REDIRECT_LIMIT must be positive, got %d", cfg.RateLimit.RedirectLimit)
+	}
+	if cfg.RateLimit.RedirectPeriod <= 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_REDIRECT_PERIOD must be positive, got %s", cfg.RateLimit.RedirectPeriod)
+	}
+	if cfg.RateLimit.BreakerThreshold < 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_BREAKER_THRESHOLD must not be negative, got %d",
+			cfg.RateLimit.BreakerThreshold)
+	}
+	if cfg.RateLimit.BreakerThreshold > 0 && cfg.RateLimit.BreakerCooldown <= 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_BREAKER_COOLDOWN must be positive, got %s", cfg.RateLimit.BreakerCooldown)
+	}
+	for _, strategy := range append([]string{cfg.ShortCode.Strategy}, cfg.ShortCode.RequestStrategies...) {
+		if !slices.Contains(shortCodeStrategies, strategy) {
+			return nil, fmt.Errorf("unknown short code strategy %q, want one of %v", strategy, shortCodeStrategies)
+		}
+	}
+	if cfg.ShortCode.Length < minShortCodeLength || cfg.ShortCode.Length > shortcode.MaxLength {
+		return nil, fmt.Errorf("SHORT_CODE_LENGTH must be between %d and %d, got %d",
+			minShortCodeLength, shortcode.MaxLength, cfg.ShortCode.Length)
+	}
+	if cfg.ShortCode.Words < minShortCodeWords || cfg.ShortCode.Words > maxShortCodeWords {
+		return nil, fmt.Errorf("SHORT_CODE_WORDS must be between %d and %d, got %d",
+			minShortCodeWords, maxShortCodeWords, cfg.ShortCode.Words)
+	}
+	if cfg.ShortCode.Key != "" && len(cfg.ShortCode.Key) < minShortCodeKeyLength {
+		return nil, fmt.Errorf("SHORT_CODE_KEY must be at least %d characters", minShortCodeKeyLength)
+	}
+	if cfg.ShortCode.MinLength < 1 || cfg.ShortCode.MinLength > shortcode.MaxLength {
+		return nil, fmt.Errorf("SHORT_CODE_MIN_LENGTH must be between 1 and %d, got %d",
+			shortcode.MaxLength, cfg.ShortCode.MinLength)
+	}
+	if cfg.Counter.Backend != "valkey" && cfg.Counter.Backend != "postgres" {
+		return nil, fmt.Errorf("COUNTER_BACKEND must be valkey or postgres, got %q", cfg.Counter.Backend)
+	}
+	if cfg.Counter.BlockSize < 1 {
+		return nil, fmt.Errorf("COUNTER_BLOCK_SIZE must be positive, got %d", cfg.Counter.BlockSize)
+	}
+	if cfg.URL.BatchMaxSize < 1 || cfg.URL.BatchMaxSize > maxURLBatchSize {
+		return nil, fmt.Errorf("URL_BATCH_MAX_SIZE must be between 1 and %d, got %d",
+			maxURLBatchSize, cfg.URL.BatchMaxSize)
+	}
+	if cfg.Policy.BlocklistReloadInterval <= 0 {
+		return nil, fmt.Errorf("POLICY_BLOCKLIST_RELOAD_INTERVAL must be positive, got %s",
+			cfg.Policy.BlocklistReloadInterval)
+	}
+	if cfg.Policy.ResolveDestinations && cfg.Policy.ResolveTimeout <= 0 {
+		return nil, fmt.Errorf("POLICY_RESOLVE_TIMEOUT must be positive, got %s", cfg.Policy.ResolveTimeout)
+	}
+	if cfg.Postgres.BreakerThreshold < 0 {
+		return nil, fmt.Errorf("POSTGRES_BREAKER_THRESHOLD must not be negative, got %d", cfg.Postgres.BreakerThreshold)
+	}
+	if cfg.Postgres.BreakerThreshold > 0 && cfg.Postgres.BreakerCooldown <= 0 {
+		return nil, fmt.Errorf("POSTGRES_BREAKER_COOLDOWN must be positive, got %s", cfg.Postgres.BreakerCooldown)
+	}
+	if cfg.Cache.FilterCapacity < 0 {
+		return nil, fmt.Errorf("CACHE_FILTER_CAPACITY must not be negative, got %d", cfg.Cache.FilterCapacity)
+	}
+	if cfg.Cache.FilterFalsePositiveRate <= 0 || cfg.Cache.FilterFalsePositiveRate >= 1 {
+		return nil, fmt.Errorf("CACHE_FILTER_FALSE_POSITIVE_RATE must be between 0 and 1, got %v",
+			cfg.Cache.FilterFalsePositiveRate)
+	}
+	if cfg.Cache.FilterCapacity > 0 && cfg.Cache.FilterRebuildInterval <= 0 {
+		return nil, fmt.Errorf("CACHE_FILTER_REBUILD_INTERVAL must be positive, got %s", cfg.Cache.FilterRebuildInterval)
+	}
+	if cfg.Cache.WarmSize < 0 {
+		return nil, fmt.Errorf("CACHE_WARM_SIZE must not be negative, got %d", cfg.Cache.WarmSize)
+	}
+	if cfg.Cache.WarmBy != "clicks" && cfg.Cache.WarmBy != "recent" {
+		return nil, fmt.Errorf("CACHE_WARM_BY must be clicks or recent, got %q", cfg.Cache.WarmBy)
+	}
 	return &cfg, nil
 }
 
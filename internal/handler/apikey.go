@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"url_shortener/internal/handler/helper"
+	"url_shortener/internal/handler/request"
+	"url_shortener/internal/model"
+)
+
+type APIKey struct {
+	apiKeyService APIKeyService
+}
+
+func NewAPIKey(
+	apiKeyService APIKeyService,
+) *APIKey {
+	return &APIKey{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// Create godoc
+//
+//	@Summary		create api key
+//	@Description	The secret is returned only in this response.
+//	@Tags			api-key
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			input	body		request.CreateAPIKey	true	"create api key"
+//	@Success		201		{object}	response.Ok{data=model.MintedAPIKey}
+//	@Failure		400		{object}	response.Fail
+//	@Failure		401		{object}	response.Fail
+//	@Failure		403		{object}	response.Fail
+//	@Failure		500		{object}	response.Fail
+//	@Router			/api-keys [post].
+func (a *APIKey) Create(w http.ResponseWriter, r *http.Request) {
+	var req request.CreateAPIKey
+	err := helper.ParseJSON(&req, r.Body)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	apiKey, err := a.apiKeyService.Mint(
+		r.Context(),
+		req.Name,
+		req.OwnerID,
+	)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	helper.Ok(w, http.StatusCreated, apiKey)
+}
+
+// List godoc
+//
+//	@Summary	list api keys
+//	@Tags		api-key
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	response.Ok{data=[]model.APIKey}
+//	@Failure	401	{object}	response.Fail
+//	@Failure	500	{object}	response.Fail
+//	@Router		/api-keys [get].
+func (a *APIKey) List(w http.ResponseWriter, r *http.Request) {
+	apiKeys, err := a.apiKeyService.List(r.Context())
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	helper.Ok(w, http.StatusOK, apiKeys)
+}
+
+// Revoke godoc
+//
+//	@Summary	revoke api key
+//	@Tags		api-key
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		int	true	"api key id"
+//	@Success	200	{object}	response.Ok{data=model.APIKey}
+//	@Failure	400	{object}	response.Fail
+//	@Failure	401	{object}	response.Fail
+//	@Failure	403	{object}	response.Fail
+//	@Failure	404	{object}	response.Fail
+//	@Failure	500	{object}	response.Fail
+//	@Router		/api-keys/{id} [delete].
+func (a *APIKey) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		helper.Fail(w, fmt.Errorf("parse id: %w", model.NewError(model.ErrInvalidInput, "invalid api key id")))
+		return
+	}
+
+	apiKey, err := a.apiKeyService.Revoke(r.Context(), id)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	helper.Ok(w, http.StatusOK, apiKey)
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+	"url_shortener/internal/handler/helper"
+)
+
+type Health struct {
+	healthService HealthService
+}
+
+func NewHealth(
+	healthService HealthService,
+) *Health {
+	return &Health{
+		healthService: healthService,
+	}
+}
+
+// Check godoc
+//
+//	@Summary		health check
+//	@Description	The status is degraded while the database is unreachable and only cached links are served; the instance still answers 200 since it keeps redirecting.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	response.Ok{data=model.Health}
+//	@Router			/health [get].
+func (h *Health) Check(w http.ResponseWriter, r *http.Request) {
+	helper.Ok(w, http.StatusOK, h.healthService.Check(r.Context()))
+}
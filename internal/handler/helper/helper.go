@@ -7,8 +7,12 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"url_shortener/internal/handler/response"
+	"url_shortener/internal/model"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -26,10 +30,14 @@ func Setup(logger *slog.Logger, validate *validator.Validate) {
 func ParseJSON(request any, body io.Reader) error {
 	err := json.NewDecoder(body).Decode(request)
 	if err != nil {
-		return fmt.Errorf("decode: %w", err)
+		return fmt.Errorf("decode: %w", model.NewError(model.ErrInvalidInput, err.Error()))
 	}
 
-	err = v.Struct(request)
+	return Validate(request)
+}
+
+func Validate(request any) error {
+	err := v.Struct(request)
 	if err != nil {
 		return fmt.Errorf("validate: %w", err)
 	}
@@ -56,7 +64,17 @@ func Ok(w http.ResponseWriter, status int, data any) {
 }
 
 func Fail(w http.ResponseWriter, err error) {
-	status := mapErrToStatus(err)
+	status, fail := Failure(err)
+	if retryAfter, ok := model.RetryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	WriteJSON(w, status, fail)
+}
+
+// Failure logs err and returns the status and body Fail would respond
+// with, for errors reported inside a larger response.
+func Failure(err error) (int, *response.Fail) {
+	status, code := mapErr(err)
 
 	level := slog.LevelDebug
 	if status >= http.StatusInternalServerError {
@@ -66,22 +84,58 @@ func Fail(w http.ResponseWriter, err error) {
 	l.LogAttrs(context.Background(), level, "error occurred",
 		slog.Any("error", err),
 		slog.Int("status", status),
+		slog.String("code", code),
 	)
 
-	WriteJSON(w, status, response.NewFail(status, err))
+	return status, response.NewFail(status, code, err)
 }
 
-func mapErrToStatus(err error) int {
+// Abort logs err and aborts a response that has already started, so the
+// client sees a broken transfer rather than one that looks complete.
+func Abort(err error) {
+	l.Error("response aborted", slog.Any("error", err))
+	panic(http.ErrAbortHandler)
+}
+
+func mapErr(err error) (int, string) {
 	var validationErrs validator.ValidationErrors
 	if errors.As(err, &validationErrs) {
-		return http.StatusBadRequest
+		return http.StatusBadRequest, response.CodeValidation
 	}
 	switch {
+	case errors.Is(err, model.ErrInvalidInput):
+		return http.StatusBadRequest, response.CodeInvalidInput
+	case errors.Is(err, model.ErrUnauthorized):
+		return http.StatusUnauthorized, response.CodeUnauthorized
+	case errors.Is(err, model.ErrForbidden):
+		return http.StatusForbidden, response.CodeForbidden
+	case errors.Is(err, model.ErrNotFound):
+		return http.StatusNotFound, response.CodeNotFound
+	case errors.Is(err, model.ErrConflict):
+		return http.StatusConflict, response.CodeConflict
+	case errors.Is(err, model.ErrGone):
+		return http.StatusGone, response.CodeGone
+	case errors.Is(err, model.ErrUnprocessable):
+		return http.StatusUnprocessableEntity, response.CodeUnprocessable
+	case errors.Is(err, model.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge, response.CodeTooLarge
+	case errors.Is(err, model.ErrRateLimited):
+		return http.StatusTooManyRequests, response.CodeRateLimited
+	case errors.Is(err, model.ErrUnavailable):
+		return http.StatusServiceUnavailable, response.CodeUnavailable
 	case errors.Is(err, context.DeadlineExceeded):
-		return http.StatusRequestTimeout
+		return http.StatusRequestTimeout, response.CodeTimeout
 	case errors.Is(err, context.Canceled):
-		return http.StatusRequestTimeout
+		return http.StatusRequestTimeout, response.CodeTimeout
 	default:
-		return http.StatusInternalServerError
+		return http.StatusInternalServerError, response.CodeInternal
+	}
+}
+
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
 }
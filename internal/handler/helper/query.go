@@ -0,0 +1,72 @@
+package helper
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+	"url_shortener/internal/model"
+)
+
+// ParseQuery fills the fields of request tagged with `query:"name"` from
+// the URL query and validates the result. Supported field kinds are string,
+// int, time.Time (RFC 3339) and pointers to them.
+func ParseQuery(request any, query url.Values) error {
+	rv := reflect.ValueOf(request).Elem()
+	rt := rv.Type()
+
+	for i := range rt.NumField() {
+		name := rt.Field(i).Tag.Get("query")
+		if name == "" || !query.Has(name) {
+			continue
+		}
+		if err := setField(rv.Field(i), query.Get(name)); err != nil {
+			return fmt.Errorf("decode: %w", model.NewError(
+				model.ErrInvalidInput,
+				fmt.Sprintf("invalid query parameter %q: %v", name, err),
+			))
+		}
+	}
+
+	if err := v.Struct(request); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	return nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	if field.Kind() == reflect.Pointer {
+		value := reflect.New(field.Type().Elem())
+		if err := setField(value.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+
+	if field.Type() == reflect.TypeFor[time.Time]() {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() { //nolint:exhaustive // only the kinds used by requests
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
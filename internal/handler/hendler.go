@@ -15,17 +15,85 @@ func Setup(mux *http.ServeMux) {
 	validate := simpledi.MustGetAs[*validator.Validate]("validate")
 
 	loggerMiddleware := simpledi.MustGetAs[*middleware.Logger]("loggerMiddleware")
+	authMiddleware := simpledi.MustGetAs[*middleware.Auth]("authMiddleware")
+	createRateLimitMiddleware := simpledi.MustGetAs[*middleware.RateLimit]("createRateLimitMiddleware")
+	redirectRateLimitMiddleware := simpledi.MustGetAs[*middleware.RateLimit]("redirectRateLimitMiddleware")
+	idempotencyMiddleware := simpledi.MustGetAs[*middleware.Idempotency]("idempotencyMiddleware")
 
 	urlHandler := simpledi.MustGetAs[*URL]("urlHandler")
+	apiKeyHandler := simpledi.MustGetAs[*APIKey]("apiKeyHandler")
+	healthHandler := simpledi.MustGetAs[*Health]("healthHandler")
 
 	helper.Setup(logger, validate)
 
 	mux.Handle("POST /urls", middleware.ChainFunc(
 		urlHandler.Create,
 		loggerMiddleware.Handle,
+		authMiddleware.Handle,
+		idempotencyMiddleware.Handle,
+		createRateLimitMiddleware.Handle,
 	))
+	mux.Handle("POST /urls/batch", middleware.ChainFunc(
+		urlHandler.CreateBatch,
+		loggerMiddleware.Handle,
+		authMiddleware.Handle,
+		idempotencyMiddleware.Handle,
+		createRateLimitMiddleware.HandleBatch,
+	))
+	mux.Handle("GET /urls", middleware.ChainFunc(
+		urlHandler.List,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("GET /urls/export", middleware.ChainFunc(
+		urlHandler.Export,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("POST /urls/import", middleware.ChainFunc(
+		urlHandler.Import,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("GET /urls/{short_code}", middleware.ChainFunc(
+		urlHandler.Get,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("GET /urls/{short_code}/stats", middleware.ChainFunc(
+		urlHandler.Stats,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("PATCH /urls/{short_code}", middleware.ChainFunc(
+		urlHandler.Update,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("DELETE /urls/{short_code}", middleware.ChainFunc(
+		urlHandler.Delete,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("POST /api-keys", middleware.ChainFunc(
+		apiKeyHandler.Create,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("GET /api-keys", middleware.ChainFunc(
+		apiKeyHandler.List,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.Handle("DELETE /api-keys/{id}", middleware.ChainFunc(
+		apiKeyHandler.Revoke,
+		loggerMiddleware.Handle,
+		authMiddleware.Require,
+	))
+	mux.HandleFunc("GET /health", healthHandler.Check)
 	mux.Handle("GET /{short_code}", middleware.ChainFunc(
 		urlHandler.Redirect,
 		loggerMiddleware.Handle,
+		redirectRateLimitMiddleware.Handle,
 	))
 }
@@ -2,10 +2,34 @@ package handler
 
 import (
 	"context"
+	"io"
+	"time"
 	"url_shortener/internal/model"
 )
 
 type URLService interface {
-	Create(ctx context.Context, originalURL string) (*model.URL, error)
+	Create(ctx context.Context, input model.CreateURL) (*model.URL, bool, error)
+	CreateBatch(ctx context.Context, inputs []model.CreateURL) ([]model.CreateURLResult, error)
 	GetOriginalURL(ctx context.Context, shortCode string) (string, error)
+	Get(ctx context.Context, shortCode string) (*model.URL, error)
+	Update(ctx context.Context, shortCode, originalURL string) (*model.URL, error)
+	Delete(ctx context.Context, shortCode string) error
+	List(ctx context.Context, filter model.URLFilter, cursor string) ([]model.URL, string, error)
+	Export(ctx context.Context, filter model.URLFilter, fn func(urls []model.URL) error) error
+	Import(ctx context.Context, r io.Reader, format string) (*model.ImportReport, error)
+}
+
+type ClickService interface {
+	Record(ctx context.Context, click model.Click)
+	Stats(ctx context.Context, shortCode string, from, to *time.Time, bucket string) (*model.ClickStats, error)
+}
+
+type APIKeyService interface {
+	Mint(ctx context.Context, name, ownerID string) (*model.MintedAPIKey, error)
+	List(ctx context.Context) ([]model.APIKey, error)
+	Revoke(ctx context.Context, id int) (*model.APIKey, error)
+}
+
+type HealthService interface {
+	Check(ctx context.Context) *model.Health
 }
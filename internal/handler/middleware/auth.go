@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"url_shortener/internal/handler/helper"
+	"url_shortener/internal/model"
+)
+
+type Auth struct {
+	authService AuthService
+}
+
+func NewAuth(
+	authService AuthService,
+) *Auth {
+	return &Auth{
+		authService: authService,
+	}
+}
+
+// Handle authenticates the bearer key when one is sent and lets anonymous
+// requests through; Require rejects them.
+func (a *Auth) Handle(next http.Handler) http.Handler {
+	return a.handle(next, false)
+}
+
+func (a *Auth) Require(next http.Handler) http.Handler {
+	return a.handle(next, true)
+}
+
+func (a *Auth) handle(next http.Handler, required bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "middleware.Auth.Handle"
+
+		secret, ok := bearerToken(r)
+		if !ok {
+			if required {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				helper.Fail(w, fmt.Errorf("%s: %w", op, model.NewError(model.ErrUnauthorized, "api key required")))
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := a.authService.Authenticate(r.Context(), secret)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			helper.Fail(w, fmt.Errorf("%s: %w", op, err))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(model.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	return token, ok && token != ""
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"url_shortener/internal/handler/helper"
+	"url_shortener/internal/model"
+)
+
+const maxIdempotencyKeyLength = 255
+
+type Idempotency struct {
+	logger *slog.Logger
+	store  IdempotencyStore
+}
+
+func NewIdempotency(
+	logger *slog.Logger,
+	store IdempotencyStore,
+) *Idempotency {
+	return &Idempotency{
+		logger: logger,
+		store:  store,
+	}
+}
+
+// Handle stores the response of a request sent with an Idempotency-Key
+// header and replays it when the same caller retries with the same key.
+// Keys are scoped per caller like rate limits, so Auth must run first; it
+// runs before RateLimit so a replay costs no tokens. Reusing a key for a
+// different request is rejected, and a retry while the first request
+// still runs gets a conflict. Server errors and rate limited responses
+// aren't stored so they can be retried, and a body over maxBodySize is
+// rejected. Like RateLimit it fails open when the store errors.
+func (i *Idempotency) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "middleware.Idempotency.Handle"
+
+		idempotencyKey := r.Header.Get("Idempotency-Key")
+		if idempotencyKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			helper.Fail(w, fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput,
+				fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))))
+			return
+		}
+
+		body, err := readBody(w, r)
+		if err != nil {
+			helper.Fail(w, fmt.Errorf("%s: %w", op, err))
+			return
+		}
+
+		key := clientKey(r) + ":" + hashHex(idempotencyKey)
+		fingerprint := hashHex(r.Method + " " + r.URL.Path + "\n" + string(body))
+
+		record, reserved, err := i.store.Reserve(r.Context(), key, fingerprint)
+		if err != nil {
+			i.logger.WarnContext(r.Context(), "idempotency store failed",
+				slog.Any("error", err),
+			)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !reserved {
+			i.replay(w, record, fingerprint)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// A client that timed out has canceled the request by now, and its
+		// retry is exactly what the stored response is for.
+		ctx := context.WithoutCancel(r.Context())
+		if rec.statusCode >= http.StatusInternalServerError || rec.statusCode == http.StatusTooManyRequests {
+			err = i.store.Release(ctx, key)
+		} else {
+			err = i.store.Save(ctx, key, &model.IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Done:        true,
+				Status:      rec.statusCode,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			})
+			if err != nil {
+				// Left reserved the key would answer every retry with a
+				// conflict until it expires.
+				i.logger.WarnContext(ctx, "idempotency store failed",
+					slog.Any("error", err),
+				)
+				err = i.store.Release(ctx, key)
+			}
+		}
+		if err != nil {
+			i.logger.WarnContext(ctx, "idempotency store failed",
+				slog.Any("error", err),
+			)
+		}
+	})
+}
+
+func (i *Idempotency) replay(w http.ResponseWriter, record *model.IdempotencyRecord, fingerprint string) {
+	const op = "middleware.Idempotency.replay"
+
+	switch {
+	case record.Fingerprint != fingerprint:
+		helper.Fail(w, fmt.Errorf("%s: %w", op, model.NewError(model.ErrUnprocessable,
+			"Idempotency-Key was already used for a different request")))
+	case !record.Done:
+		helper.Fail(w, fmt.Errorf("%s: %w", op, model.NewError(model.ErrConflict,
+			"a request with this Idempotency-Key is still in progress")))
+	default:
+		if record.ContentType != "" {
+			w.Header().Set("Content-Type", record.ContentType)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(record.Status)
+		if _, err := w.Write(record.Body); err != nil {
+			i.logger.Error("failed to write replayed response", slog.Any("error", err))
+		}
+	}
+}
+
+// recorder passes the response through and keeps a copy of it.
+type recorder struct {
+	http.ResponseWriter
+
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func hashHex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,130 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"url_shortener/internal/handler/middleware"
+	"url_shortener/internal/model"
+)
+
+// fakeStore fails like the Valkey store does when ctx is canceled.
+type fakeStore struct {
+	mu      sync.Mutex
+	records map[string]*model.IdempotencyRecord
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{records: make(map[string]*model.IdempotencyRecord)}
+}
+
+func (s *fakeStore) Reserve(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; ok {
+		return record, false, nil
+	}
+	s.records[key] = &model.IdempotencyRecord{Fingerprint: fingerprint}
+	return nil, true, nil
+}
+
+func (s *fakeStore) Save(ctx context.Context, key string, record *model.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record
+	return nil
+}
+
+func (s *fakeStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func send(t *testing.T, h http.Handler, ctx context.Context, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/urls", strings.NewReader(body))
+	r.Header.Set("Idempotency-Key", "key-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysAfterClientCanceled(t *testing.T) {
+	calls := 0
+	var cancel context.CancelFunc
+	h := middleware.NewIdempotency(discardLogger(), newFakeStore()).Handle(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls++
+			// The client times out while the link is being created.
+			cancel()
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"short_code":"abc"}`))
+		}),
+	)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancel = cancelFunc
+	send(t, h, ctx, `{"url":"https://example.com"}`)
+
+	w := send(t, h, context.Background(), `{"url":"https://example.com"}`)
+	if calls != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls)
+	}
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry got %d, replayed %q, want the stored 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if got := w.Body.String(); got != `{"short_code":"abc"}` {
+		t.Fatalf("retry body = %s", got)
+	}
+}
+
+func TestIdempotencyDoesNotStoreRetryableResponses(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			calls := 0
+			h := middleware.NewIdempotency(discardLogger(), newFakeStore()).Handle(
+				http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					calls++
+					if calls == 1 {
+						w.WriteHeader(status)
+						return
+					}
+					w.WriteHeader(http.StatusCreated)
+				}),
+			)
+
+			send(t, h, context.Background(), `{}`)
+			w := send(t, h, context.Background(), `{}`)
+			if calls != 2 || w.Code != http.StatusCreated {
+				t.Fatalf("retry got %d after %d calls, want a fresh 201", w.Code, calls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	h := middleware.NewIdempotency(discardLogger(), newFakeStore()).Handle(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}),
+	)
+
+	send(t, h, context.Background(), `{"url":"https://a.example"}`)
+	if w := send(t, h, context.Background(), `{"url":"https://b.example"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key got %d, want 422", w.Code)
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"context"
+	"url_shortener/internal/model"
+)
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, cost int) (*model.RateLimit, error)
+}
+
+type AuthService interface {
+	Authenticate(ctx context.Context, secret string) (*model.Principal, error)
+}
+
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, bool, error)
+	Save(ctx context.Context, key string, record *model.IdempotencyRecord) error
+	Release(ctx context.Context, key string) error
+}
@@ -69,3 +69,9 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	rw.size += size
 	return size, err
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, for
+// handlers that flush or extend deadlines while streaming.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
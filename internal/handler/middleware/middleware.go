@@ -1,6 +1,16 @@
 package middleware
 
-import "net/http"
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"url_shortener/internal/model"
+)
+
+// maxBodySize bounds the request bodies middlewares read ahead of the
+// handler.
+const maxBodySize = 1 << 20
 
 func Chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	for i := len(middlewares) - 1; i >= 0; i-- {
@@ -12,3 +22,17 @@ func Chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.
 func ChainFunc(h http.HandlerFunc, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	return Chain(h, middlewares...)
 }
+
+// readBody reads the body of r and puts it back for the next handler.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, model.NewError(model.ErrTooLarge, "request body is too large")
+	}
+	if err != nil {
+		return nil, model.NewError(model.ErrInvalidInput, err.Error())
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,20 @@
+package middleware_test
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"url_shortener/internal/handler/helper"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestMain(m *testing.M) {
+	helper.Setup(discardLogger(), validator.New())
+	os.Exit(m.Run())
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
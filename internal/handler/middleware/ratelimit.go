@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"url_shortener/internal/handler/helper"
+	"url_shortener/internal/model"
+)
+
+type RateLimit struct {
+	logger  *slog.Logger
+	limiter Limiter
+}
+
+func NewRateLimit(
+	logger *slog.Logger,
+	limiter Limiter,
+) *RateLimit {
+	return &RateLimit{
+		logger:  logger,
+		limiter: limiter,
+	}
+}
+
+// Handle fails open: when the limiter errors the request goes through.
+func (rl *RateLimit) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rl.serve(w, r, next, 1)
+	})
+}
+
+// HandleBatch charges a token per item of a {"items": [...]} body, so a
+// batch can't create more links than the same number of single requests.
+// A batch of more items than the limit could never be allowed and is
+// rejected as too large. A body that doesn't decode costs one token and is
+// left to the handler to reject.
+func (rl *RateLimit) HandleBatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const op = "middleware.RateLimit.HandleBatch"
+
+		body, err := readBody(w, r)
+		if err != nil {
+			helper.Fail(w, fmt.Errorf("%s: %w", op, err))
+			return
+		}
+
+		var batch struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		cost := 1
+		if json.Unmarshal(body, &batch) == nil {
+			cost = max(len(batch.Items), 1)
+		}
+
+		rl.serve(w, r, next, cost)
+	})
+}
+
+func (rl *RateLimit) serve(w http.ResponseWriter, r *http.Request, next http.Handler, cost int) {
+	const op = "middleware.RateLimit.Handle"
+
+	result, err := rl.limiter.Allow(r.Context(), clientKey(r), cost)
+	if err != nil {
+		rl.logger.WarnContext(r.Context(), "rate limiter failed",
+			slog.Any("error", err),
+		)
+		next.ServeHTTP(w, r)
+		return
+	}
+	if cost > result.Limit {
+		helper.Fail(w, fmt.Errorf("%s: %w", op, model.NewError(model.ErrTooLarge,
+			fmt.Sprintf("a batch may have at most %d items", result.Limit))))
+		return
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+	if !result.Allowed {
+		w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+		helper.Fail(w, fmt.Errorf("%s: %w", op, model.ErrRateLimited))
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+// clientKey identifies the caller by the API key authenticated by Auth,
+// which must run first, and by IP address otherwise.
+func clientKey(r *http.Request) string {
+	principal, ok := model.PrincipalFrom(r.Context())
+	switch {
+	case !ok:
+		return "ip:" + helper.ClientIP(r)
+	case principal.Admin:
+		return "admin"
+	default:
+		return "key:" + strconv.Itoa(principal.KeyID)
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,45 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url_shortener/internal/handler/middleware"
+	"url_shortener/internal/repository/memory"
+)
+
+func batchBody(items int) string {
+	return `{"items":[` + strings.TrimSuffix(strings.Repeat(`{"url":"https://example.com"},`, items), ",") + `]}`
+}
+
+func TestRateLimitHandleBatch(t *testing.T) {
+	calls := 0
+	h := middleware.NewRateLimit(discardLogger(), memory.NewRateLimiter(10, time.Minute, 10)).HandleBatch(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/urls/batch", strings.NewReader(body)))
+		return w
+	}
+
+	// More items than the limit never fit, however long the caller waits.
+	if w := post(batchBody(1000)); w.Code != http.StatusRequestEntityTooLarge || calls != 0 {
+		t.Fatalf("batch over the limit got %d after %d calls, want 413", w.Code, calls)
+	}
+
+	if w := post(batchBody(6)); w.Code != http.StatusOK {
+		t.Fatalf("batch of 6 got %d, want 200", w.Code)
+	}
+	if got := post(batchBody(5)); got.Code != http.StatusTooManyRequests || got.Header().Get("Retry-After") == "" {
+		t.Fatalf("batch of 5 with 4 tokens left got %d, want 429 with Retry-After", got.Code)
+	}
+	if w := post(batchBody(4)); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("batch of 4 got %d, remaining %s, want 200 and 0", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+}
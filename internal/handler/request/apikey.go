@@ -0,0 +1,6 @@
+package request
+
+type CreateAPIKey struct {
+	Name    string `json:"name"     validate:"required,max=255"`
+	OwnerID string `json:"owner_id" validate:"omitempty,uuid"`
+}
@@ -1,5 +1,71 @@
 package request
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 type CreateURL struct {
+	OriginalURL   string     `json:"original_url"   validate:"required,url"`
+	Alias         string     `json:"alias"`
+	Strategy      string     `json:"strategy"       validate:"omitempty,oneof=counter random hash words" enums:"counter,random,hash,words"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	ExpiresIn     Duration   `json:"expires_in"     swaggertype:"string" example:"72h"`
+	ReuseExisting bool       `json:"reuse_existing"`
+}
+
+// CreateURLs items are validated one by one, so an invalid item fails only
+// itself.
+type CreateURLs struct {
+	Items []CreateURL `json:"items" validate:"required,min=1"`
+}
+
+// Duration accepts Go duration strings such as "90m" or "72h" in JSON.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+type UpdateURL struct {
 	OriginalURL string `json:"original_url" validate:"required,url"`
 }
+
+type ListURLs struct {
+	Cursor      string     `query:"cursor"`
+	Limit       int        `query:"limit"        validate:"min=1,max=100"`
+	CreatedFrom *time.Time `query:"created_from"`
+	CreatedTo   *time.Time `query:"created_to"`
+	Search      string     `query:"q"            validate:"max=2048"`
+}
+
+type ExportURLs struct {
+	Format      string     `query:"format"       validate:"oneof=csv jsonl" enums:"csv,jsonl"`
+	CreatedFrom *time.Time `query:"created_from"`
+	CreatedTo   *time.Time `query:"created_to"`
+	Search      string     `query:"q"            validate:"max=2048"`
+}
+
+type ImportURLs struct {
+	Format string `query:"format" validate:"oneof=csv jsonl" enums:"csv,jsonl"`
+}
+
+type URLStats struct {
+	From   *time.Time `query:"from"`
+	To     *time.Time `query:"to"`
+	Bucket string     `query:"bucket" validate:"oneof=minute hour day"`
+}
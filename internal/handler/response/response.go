@@ -3,15 +3,60 @@ package response
 import (
 	"errors"
 	"net/http"
+	"url_shortener/internal/model"
 
 	"github.com/go-playground/validator/v10"
 )
 
+const (
+	CodeValidation    = "validation_failed"
+	CodeInvalidInput  = "invalid_input"
+	CodeUnauthorized  = "unauthorized"
+	CodeForbidden     = "forbidden"
+	CodeNotFound      = "not_found"
+	CodeConflict      = "conflict"
+	CodeGone          = "gone"
+	CodeUnprocessable = "unprocessable"
+	CodeTooLarge      = "too_large"
+	CodeRateLimited   = "rate_limited"
+	CodeTimeout       = "timeout"
+	CodeUnavailable   = "unavailable"
+	CodeInternal      = "internal"
+)
+
 type Ok struct {
 	Data any `json:"data"`
 }
 
+type Page struct {
+	Items      any    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// BatchItem is the outcome of one item of a batch request: the status and
+// data or error it would have got as a request of its own.
+type BatchItem struct {
+	Status int   `json:"status"`
+	Data   any   `json:"data,omitempty"`
+	Error  *Fail `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Imported int             `json:"imported"`
+	Failed   []ImportFailure `json:"failed"`
+}
+
+// ImportFailure is a row that was not imported, with the status and error
+// it would have got as a create of its own.
+type ImportFailure struct {
+	Line      int    `json:"line"`
+	ShortCode string `json:"short_code,omitempty"`
+	Status    int    `json:"status"`
+	Error     *Fail  `json:"error"`
+}
+
 type Fail struct {
+	Code   string   `json:"code"`
 	Error  string   `json:"error,omitempty"`
 	Errors []string `json:"errors,omitempty"`
 }
@@ -22,14 +67,21 @@ func NewOk(data any) *Ok {
 	}
 }
 
-func NewFail(status int, err error) *Fail {
+func NewPage(items any, nextCursor string) *Page {
+	return &Page{
+		Items:      items,
+		NextCursor: nextCursor,
+	}
+}
+
+func NewFail(status int, code string, err error) *Fail {
 	var validationErrs validator.ValidationErrors
 	if errors.As(err, &validationErrs) {
 		responseErrs := make([]string, len(validationErrs))
 		for i, validationErr := range validationErrs {
 			responseErrs[i] = validationErr.Error()
 		}
-		return &Fail{Errors: responseErrs}
+		return &Fail{Code: code, Errors: responseErrs}
 	}
 
 	responseErr := http.StatusText(status)
@@ -37,10 +89,14 @@ func NewFail(status int, err error) *Fail {
 		responseErr = unwrapErr(err).Error()
 	}
 
-	return &Fail{Error: responseErr}
+	return &Fail{Code: code, Error: responseErr}
 }
 
 func unwrapErr(err error) error {
+	var modelErr *model.Error
+	if errors.As(err, &modelErr) {
+		return modelErr
+	}
 	for errors.Unwrap(err) != nil {
 		err = errors.Unwrap(err)
 	}
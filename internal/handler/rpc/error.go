@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	urlshortenerv1 "url_shortener/internal/gen/url_shortener/v1"
+	"url_shortener/internal/model"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// toStatus is the gRPC counterpart of helper.Fail: domain errors keep their
+// message, anything unexpected is reported as a bare Internal. A retry hint
+// is passed on as RetryInfo.
+func toStatus(err error) error {
+	code := mapErr(err)
+	var st *status.Status
+	switch code {
+	case codes.Internal:
+		return status.Error(code, "internal error")
+	case codes.Unavailable:
+		st = status.New(code, "service unavailable")
+	default:
+		st = status.New(code, message(err))
+	}
+
+	if retryAfter, ok := model.RetryAfter(err); ok {
+		detailed, detailErr := st.WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(retryAfter),
+		})
+		if detailErr == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+// toBatchError reports the error of one batch item the way toStatus would
+// report it for the whole call.
+func toBatchError(err error) *urlshortenerv1.CreateBatchError {
+	st, _ := status.FromError(toStatus(err))
+	return &urlshortenerv1.CreateBatchError{
+		Code:    int32(st.Code()),
+		Message: st.Message(),
+	}
+}
+
+func mapErr(err error) codes.Code {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return codes.InvalidArgument
+	}
+	switch {
+	case errors.Is(err, model.ErrInvalidInput):
+		return codes.InvalidArgument
+	case errors.Is(err, model.ErrUnauthorized):
+		return codes.Unauthenticated
+	case errors.Is(err, model.ErrForbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, model.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, model.ErrConflict):
+		return codes.AlreadyExists
+	case errors.Is(err, model.ErrGone):
+		return codes.FailedPrecondition
+	case errors.Is(err, model.ErrUnprocessable):
+		return codes.FailedPrecondition
+	case errors.Is(err, model.ErrTooLarge):
+		return codes.ResourceExhausted
+	case errors.Is(err, model.ErrRateLimited):
+		return codes.ResourceExhausted
+	case errors.Is(err, model.ErrUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.Internal
+	}
+}
+
+func message(err error) string {
+	var modelErr *model.Error
+	if errors.As(err, &modelErr) {
+		return modelErr.Error()
+	}
+	for errors.Unwrap(err) != nil {
+		err = errors.Unwrap(err)
+	}
+	return err.Error()
+}
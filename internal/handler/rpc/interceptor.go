@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	urlshortenerv1 "url_shortener/internal/gen/url_shortener/v1"
+	"url_shortener/internal/model"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Logger logs every call the way middleware.Logger logs HTTP requests and
+// turns returned errors into gRPC statuses.
+func Logger(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		start := time.Now()
+
+		requestID := firstMetadata(ctx, "x-request-id")
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+
+		logger := logger.With(
+			slog.String("method", info.FullMethod),
+			slog.String("request_id", requestID),
+		)
+
+		logger.InfoContext(ctx, "request started")
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			code := mapErr(err)
+			level := slog.LevelDebug
+			if code == codes.Internal {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(ctx, level, "error occurred",
+				slog.Any("error", err),
+				slog.String("code", code.String()),
+			)
+			err = toStatus(err)
+		}
+
+		logger.InfoContext(ctx, "request finished",
+			slog.String("status_code", status.Code(err).String()),
+			slog.Duration("duration", time.Since(start)),
+		)
+
+		return resp, err
+	}
+}
+
+// Auth authenticates the bearer key in the authorization metadata when one
+// is sent. Whether a principal is required is left to the service, as for
+// POST /urls over HTTP.
+func Auth(authService AuthService) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		const op = "rpc.Auth"
+
+		secret, ok := strings.CutPrefix(firstMetadata(ctx, "authorization"), "Bearer ")
+		if !ok || secret == "" {
+			return handler(ctx, req)
+		}
+
+		principal, err := authService.Authenticate(ctx, strings.TrimSpace(secret))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return handler(model.WithPrincipal(ctx, principal), req)
+	}
+}
+
+// RateLimit applies the HTTP rate limits to the matching calls: limiters
+// maps a full method name to its limiter, and methods without one are not
+// limited. CreateBatch costs a token per item and is rejected when it has
+// more items than the limit, as POST /urls/batch is. Callers are keyed by
+// the principal authenticated by Auth, which must run first, and by peer
+// IP otherwise. Like middleware.RateLimit it fails open when the limiter
+// does.
+func RateLimit(logger *slog.Logger, limiters map[string]Limiter) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		const op = "rpc.RateLimit"
+
+		limiter, ok := limiters[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		cost := 1
+		if batch, ok := req.(*urlshortenerv1.CreateBatchRequest); ok {
+			cost = max(len(batch.GetItems()), 1)
+		}
+
+		result, err := limiter.Allow(ctx, clientKey(ctx), cost)
+		if err != nil {
+			logger.WarnContext(ctx, "rate limiter failed",
+				slog.String("method", info.FullMethod),
+				slog.Any("error", err),
+			)
+			return handler(ctx, req)
+		}
+		if cost > result.Limit {
+			return nil, fmt.Errorf("%s: %w", op, model.NewError(model.ErrTooLarge,
+				fmt.Sprintf("a batch may have at most %d items", result.Limit)))
+		}
+		if !result.Allowed {
+			return nil, fmt.Errorf("%s: %w", op, model.NewRetryError(model.ErrRateLimited, result.RetryAfter))
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// clientKey matches the keys of middleware.RateLimit, so a caller shares
+// its budget between HTTP and gRPC.
+func clientKey(ctx context.Context) string {
+	principal, ok := model.PrincipalFrom(ctx)
+	switch {
+	case ok && principal.Admin:
+		return "admin"
+	case ok:
+		return "key:" + strconv.Itoa(principal.KeyID)
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:" + host
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,24 @@
+package rpc
+
+import (
+	"context"
+	"url_shortener/internal/model"
+)
+
+type URLService interface {
+	Create(ctx context.Context, input model.CreateURL) (*model.URL, bool, error)
+	CreateBatch(ctx context.Context, inputs []model.CreateURL) ([]model.CreateURLResult, error)
+	GetOriginalURL(ctx context.Context, shortCode string) (string, error)
+	Get(ctx context.Context, shortCode string) (*model.URL, error)
+	Update(ctx context.Context, shortCode, originalURL string) (*model.URL, error)
+	Delete(ctx context.Context, shortCode string) error
+	List(ctx context.Context, filter model.URLFilter, cursor string) ([]model.URL, string, error)
+}
+
+type AuthService interface {
+	Authenticate(ctx context.Context, secret string) (*model.Principal, error)
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, cost int) (*model.RateLimit, error)
+}
@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"log/slog"
+	urlshortenerv1 "url_shortener/internal/gen/url_shortener/v1"
+	"url_shortener/internal/service"
+
+	"github.com/eerzho/simpledi"
+	"google.golang.org/grpc"
+)
+
+func NewServer() *grpc.Server {
+	logger := simpledi.MustGetAs[*slog.Logger]("logger")
+	apiKeyService := simpledi.MustGetAs[*service.APIKey]("apiKeyService")
+
+	createRateLimiter := simpledi.MustGetAs[Limiter]("createRateLimiter")
+	redirectRateLimiter := simpledi.MustGetAs[Limiter]("redirectRateLimiter")
+
+	urlServer := simpledi.MustGetAs[*URL]("urlRPCHandler")
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			Logger(logger),
+			Auth(apiKeyService),
+			RateLimit(logger, map[string]Limiter{
+				urlshortenerv1.URLService_Create_FullMethodName:      createRateLimiter,
+				urlshortenerv1.URLService_CreateBatch_FullMethodName: createRateLimiter,
+				urlshortenerv1.URLService_Resolve_FullMethodName:     redirectRateLimiter,
+			}),
+		),
+	)
+
+	urlshortenerv1.RegisterURLServiceServer(server, urlServer)
+
+	return server
+}
@@ -0,0 +1,252 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+	urlshortenerv1 "url_shortener/internal/gen/url_shortener/v1"
+	"url_shortener/internal/handler/request"
+	"url_shortener/internal/model"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultListLimit = 20
+
+// URL serves url_shortener.v1.URLService on top of the same service.URL
+// as the HTTP handlers. Requests are validated with the HTTP request types
+// so both transports accept exactly the same input.
+type URL struct {
+	urlshortenerv1.UnimplementedURLServiceServer
+
+	validate   *validator.Validate
+	urlService URLService
+}
+
+func NewURL(
+	validate *validator.Validate,
+	urlService URLService,
+) *URL {
+	return &URL{
+		validate:   validate,
+		urlService: urlService,
+	}
+}
+
+func (u *URL) Create(
+	ctx context.Context,
+	req *urlshortenerv1.CreateRequest,
+) (*urlshortenerv1.CreateResponse, error) {
+	const op = "rpc.URL.Create"
+
+	input, err := u.toCreateURL(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, created, err := u.urlService.Create(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &urlshortenerv1.CreateResponse{Url: toProto(url), Created: created}, nil
+}
+
+// CreateBatch fails as a whole only when the batch can't be processed at
+// all; items that fail on their own carry their error in their result.
+func (u *URL) CreateBatch(
+	ctx context.Context,
+	req *urlshortenerv1.CreateBatchRequest,
+) (*urlshortenerv1.CreateBatchResponse, error) {
+	const op = "rpc.URL.CreateBatch"
+
+	results := make([]*urlshortenerv1.CreateBatchResult, len(req.GetItems()))
+	inputs := make([]model.CreateURL, 0, len(req.GetItems()))
+	indexes := make([]int, 0, len(req.GetItems()))
+	for i, item := range req.GetItems() {
+		input, err := u.toCreateURL(item)
+		if err != nil {
+			results[i] = &urlshortenerv1.CreateBatchResult{Error: toBatchError(err)}
+			continue
+		}
+		inputs = append(inputs, input)
+		indexes = append(indexes, i)
+	}
+
+	if len(inputs) > 0 || len(results) == 0 {
+		created, err := u.urlService.CreateBatch(ctx, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		for j, result := range created {
+			if result.Err != nil {
+				results[indexes[j]] = &urlshortenerv1.CreateBatchResult{Error: toBatchError(result.Err)}
+				continue
+			}
+			results[indexes[j]] = &urlshortenerv1.CreateBatchResult{Url: toProto(result.URL), Created: result.Created}
+		}
+	}
+
+	return &urlshortenerv1.CreateBatchResponse{Results: results}, nil
+}
+
+func (u *URL) Get(
+	ctx context.Context,
+	req *urlshortenerv1.GetRequest,
+) (*urlshortenerv1.GetResponse, error) {
+	const op = "rpc.URL.Get"
+
+	url, err := u.urlService.Get(ctx, req.GetShortCode())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &urlshortenerv1.GetResponse{Url: toProto(url)}, nil
+}
+
+// Resolve returns the destination without redirecting; unlike GET
+// /{short_code} it is not counted as a click.
+func (u *URL) Resolve(
+	ctx context.Context,
+	req *urlshortenerv1.ResolveRequest,
+) (*urlshortenerv1.ResolveResponse, error) {
+	const op = "rpc.URL.Resolve"
+
+	original, err := u.urlService.GetOriginalURL(ctx, req.GetShortCode())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &urlshortenerv1.ResolveResponse{OriginalUrl: original}, nil
+}
+
+func (u *URL) Update(
+	ctx context.Context,
+	req *urlshortenerv1.UpdateRequest,
+) (*urlshortenerv1.UpdateResponse, error) {
+	const op = "rpc.URL.Update"
+
+	input := request.UpdateURL{OriginalURL: req.GetOriginalUrl()}
+	if err := u.validate.Struct(input); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, err := u.urlService.Update(ctx, req.GetShortCode(), input.OriginalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &urlshortenerv1.UpdateResponse{Url: toProto(url)}, nil
+}
+
+func (u *URL) Delete(
+	ctx context.Context,
+	req *urlshortenerv1.DeleteRequest,
+) (*urlshortenerv1.DeleteResponse, error) {
+	const op = "rpc.URL.Delete"
+
+	err := u.urlService.Delete(ctx, req.GetShortCode())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &urlshortenerv1.DeleteResponse{}, nil
+}
+
+func (u *URL) List(
+	ctx context.Context,
+	req *urlshortenerv1.ListRequest,
+) (*urlshortenerv1.ListResponse, error) {
+	const op = "rpc.URL.List"
+
+	input := request.ListURLs{
+		Cursor: req.GetCursor(),
+		Limit:  int(req.GetLimit()),
+		Search: req.GetQuery(),
+	}
+	if input.Limit == 0 {
+		input.Limit = defaultListLimit
+	}
+	if req.GetCreatedFrom() != nil {
+		createdFrom := req.GetCreatedFrom().AsTime()
+		input.CreatedFrom = &createdFrom
+	}
+	if req.GetCreatedTo() != nil {
+		createdTo := req.GetCreatedTo().AsTime()
+		input.CreatedTo = &createdTo
+	}
+	if err := u.validate.Struct(input); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	urls, nextCursor, err := u.urlService.List(
+		ctx,
+		model.URLFilter{
+			CreatedFrom: input.CreatedFrom,
+			CreatedTo:   input.CreatedTo,
+			Search:      input.Search,
+			Limit:       input.Limit,
+		},
+		input.Cursor,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &urlshortenerv1.ListResponse{
+		Urls:       make([]*urlshortenerv1.URL, 0, len(urls)),
+		NextCursor: nextCursor,
+	}
+	for i := range urls {
+		resp.Urls = append(resp.Urls, toProto(&urls[i]))
+	}
+
+	return resp, nil
+}
+
+// toCreateURL validates req with the HTTP request type and converts it to
+// the service input.
+func (u *URL) toCreateURL(req *urlshortenerv1.CreateRequest) (model.CreateURL, error) {
+	input := request.CreateURL{
+		OriginalURL: req.GetOriginalUrl(),
+		Alias:       req.GetAlias(),
+		Strategy:    req.GetStrategy(),
+	}
+	if req.GetExpiresAt() != nil {
+		expiresAt := req.GetExpiresAt().AsTime()
+		input.ExpiresAt = &expiresAt
+	}
+	if req.GetExpiresIn() != nil {
+		input.ExpiresIn = request.Duration(req.GetExpiresIn().AsDuration())
+	}
+	if err := u.validate.Struct(input); err != nil {
+		return model.CreateURL{}, err
+	}
+
+	return model.CreateURL{
+		OriginalURL:   input.OriginalURL,
+		Alias:         input.Alias,
+		Strategy:      input.Strategy,
+		ExpiresAt:     input.ExpiresAt,
+		ExpiresIn:     time.Duration(input.ExpiresIn),
+		ReuseExisting: req.GetReuseExisting(),
+	}, nil
+}
+
+func toProto(url *model.URL) *urlshortenerv1.URL {
+	msg := &urlshortenerv1.URL{
+		Id:          int64(url.ID),
+		ShortCode:   url.ShortCode,
+		OriginalUrl: url.OriginalURL,
+		CreatedAt:   timestamppb.New(url.CreatedAt),
+		UpdatedAt:   timestamppb.New(url.UpdatedAt),
+	}
+	if url.ExpiresAt != nil {
+		msg.ExpiresAt = timestamppb.New(*url.ExpiresAt)
+	}
+	if url.OwnerID != nil {
+		msg.OwnerId = *url.OwnerID
+	}
+	return msg
+}
@@ -1,20 +1,38 @@
 package handler
 
 import (
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 	"url_shortener/internal/handler/helper"
 	"url_shortener/internal/handler/request"
+	"url_shortener/internal/handler/response"
+	"url_shortener/internal/model"
+	"url_shortener/internal/utils/linkfile"
+)
+
+const (
+	defaultListLimit   = 20
+	defaultStatsBucket = "day"
+	// transferIdleTimeout replaces the server read and write timeouts on
+	// exports and imports: they may take any time as long as data keeps
+	// moving.
+	transferIdleTimeout = time.Minute
 )
 
 type URL struct {
-	urlService URLService
+	urlService   URLService
+	clickService ClickService
 }
 
 func NewURL(
 	urlService URLService,
+	clickService ClickService,
 ) *URL {
 	return &URL{
-		urlService: urlService,
+		urlService:   urlService,
+		clickService: clickService,
 	}
 }
 
@@ -22,12 +40,22 @@ func NewURL(
 //
 //	@Summary	create url
 //	@Tags		url
+//	@Security	BearerAuth
 //	@Accept		json
 //	@Produce	json
-//	@Param		input	body		request.CreateURL	true	"create url"
-//	@Success	201		{object}	response.Ok{data=model.URL}
-//	@Failure	400		{object}	response.Fail
-//	@Failure	500		{object}	response.Fail
+//	@Param		Idempotency-Key	header		string				false	"replays the stored response of an earlier request with the same key"
+//	@Param		input			body		request.CreateURL	true	"create url"
+//	@Success	200				{object}	response.Ok{data=model.URL}	"existing link reused"
+//	@Success	201				{object}	response.Ok{data=model.URL}
+//	@Failure	400				{object}	response.Fail
+//	@Failure	401				{object}	response.Fail
+//	@Failure	403				{object}	response.Fail
+//	@Failure	409				{object}	response.Fail
+//	@Failure	413				{object}	response.Fail
+//	@Failure	422				{object}	response.Fail
+//	@Failure	429				{object}	response.Fail
+//	@Failure	500				{object}	response.Fail
+//	@Failure	503				{object}	response.Fail
 //	@Router		/urls [post].
 func (u *URL) Create(w http.ResponseWriter, r *http.Request) {
 	var req request.CreateURL
@@ -37,16 +65,74 @@ func (u *URL) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url, err := u.urlService.Create(
+	url, created, err := u.urlService.Create(
 		r.Context(),
-		req.OriginalURL,
+		toCreateURL(req),
 	)
 	if err != nil {
 		helper.Fail(w, err)
 		return
 	}
 
-	helper.Ok(w, http.StatusCreated, url)
+	helper.Ok(w, createdStatus(created), url)
+}
+
+// CreateBatch godoc
+//
+//	@Summary		create urls in bulk
+//	@Description	Creates every item like POST /urls. The response lists one result per item, in order, with the status the item would have got on its own; one bad item doesn't fail the others. Each item counts against the create rate limit, and a batch of more items than the limit is rejected with 413.
+//	@Tags			url
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			Idempotency-Key	header		string				false	"replays the stored response of an earlier request with the same key"
+//	@Param			input			body		request.CreateURLs	true	"create urls"
+//	@Success		200				{object}	response.Ok{data=[]response.BatchItem}
+//	@Failure		400				{object}	response.Fail
+//	@Failure		401				{object}	response.Fail
+//	@Failure		409				{object}	response.Fail
+//	@Failure		413				{object}	response.Fail
+//	@Failure		422				{object}	response.Fail
+//	@Failure		429				{object}	response.Fail
+//	@Failure		500				{object}	response.Fail
+//	@Failure		503				{object}	response.Fail
+//	@Router			/urls/batch [post].
+func (u *URL) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req request.CreateURLs
+	err := helper.ParseJSON(&req, r.Body)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	items := make([]response.BatchItem, len(req.Items))
+	inputs := make([]model.CreateURL, 0, len(req.Items))
+	indexes := make([]int, 0, len(req.Items))
+	for i, item := range req.Items {
+		if err := helper.Validate(item); err != nil {
+			items[i].Status, items[i].Error = helper.Failure(err)
+			continue
+		}
+		inputs = append(inputs, toCreateURL(item))
+		indexes = append(indexes, i)
+	}
+
+	if len(inputs) > 0 {
+		results, err := u.urlService.CreateBatch(r.Context(), inputs)
+		if err != nil {
+			helper.Fail(w, err)
+			return
+		}
+		for j, result := range results {
+			if result.Err != nil {
+				items[indexes[j]].Status, items[indexes[j]].Error = helper.Failure(result.Err)
+				continue
+			}
+			items[indexes[j]] = response.BatchItem{Status: createdStatus(result.Created), Data: result.URL}
+		}
+	}
+
+	helper.Ok(w, http.StatusOK, items)
 }
 
 // Redirect godoc
@@ -56,7 +142,11 @@ func (u *URL) Create(w http.ResponseWriter, r *http.Request) {
 //	@Param		short_code	path	string	true	"short code"
 //	@Success	302
 //	@Failure	400	{object}	response.Fail
+//	@Failure	404	{object}	response.Fail
+//	@Failure	410	{object}	response.Fail
+//	@Failure	429	{object}	response.Fail
 //	@Failure	500	{object}	response.Fail
+//	@Failure	503	{object}	response.Fail
 //	@Router		/{short_code} [get].
 func (u *URL) Redirect(w http.ResponseWriter, r *http.Request) {
 	original, err := u.urlService.GetOriginalURL(
@@ -68,5 +158,368 @@ func (u *URL) Redirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	u.clickService.Record(r.Context(), model.Click{
+		ShortCode:      r.PathValue("short_code"),
+		ClickedAt:      time.Now().UTC(),
+		Referrer:       r.Referer(),
+		UserAgent:      r.UserAgent(),
+		IP:             helper.ClientIP(r),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+	})
+
 	http.Redirect(w, r, original, http.StatusFound)
 }
+
+// Get godoc
+//
+//	@Summary	get url
+//	@Tags		url
+//	@Security	BearerAuth
+//	@Produce	json
+//	@Param		short_code	path		string	true	"short code"
+//	@Success	200			{object}	response.Ok{data=model.URL}
+//	@Failure	401			{object}	response.Fail
+//	@Failure	403			{object}	response.Fail
+//	@Failure	404			{object}	response.Fail
+//	@Failure	500			{object}	response.Fail
+//	@Failure	503			{object}	response.Fail
+//	@Router		/urls/{short_code} [get].
+func (u *URL) Get(w http.ResponseWriter, r *http.Request) {
+	url, err := u.urlService.Get(
+		r.Context(),
+		r.PathValue("short_code"),
+	)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	helper.Ok(w, http.StatusOK, url)
+}
+
+// Update godoc
+//
+//	@Summary	update url
+//	@Tags		url
+//	@Security	BearerAuth
+//	@Accept		json
+//	@Produce	json
+//	@Param		short_code	path		string				true	"short code"
+//	@Param		input		body		request.UpdateURL	true	"update url"
+//	@Success	200			{object}	response.Ok{data=model.URL}
+//	@Failure	400			{object}	response.Fail
+//	@Failure	401			{object}	response.Fail
+//	@Failure	403			{object}	response.Fail
+//	@Failure	404			{object}	response.Fail
+//	@Failure	422			{object}	response.Fail
+//	@Failure	500			{object}	response.Fail
+//	@Failure	503			{object}	response.Fail
+//	@Router		/urls/{short_code} [patch].
+func (u *URL) Update(w http.ResponseWriter, r *http.Request) {
+	var req request.UpdateURL
+	err := helper.ParseJSON(&req, r.Body)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	url, err := u.urlService.Update(
+		r.Context(),
+		r.PathValue("short_code"),
+		req.OriginalURL,
+	)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	helper.Ok(w, http.StatusOK, url)
+}
+
+// Delete godoc
+//
+//	@Summary	delete url
+//	@Tags		url
+//	@Security	BearerAuth
+//	@Param		short_code	path	string	true	"short code"
+//	@Success	204
+//	@Failure	401	{object}	response.Fail
+//	@Failure	403	{object}	response.Fail
+//	@Failure	404	{object}	response.Fail
+//	@Failure	500	{object}	response.Fail
+//	@Failure	503	{object}	response.Fail
+//	@Router		/urls/{short_code} [delete].
+func (u *URL) Delete(w http.ResponseWriter, r *http.Request) {
+	err := u.urlService.Delete(
+		r.Context(),
+		r.PathValue("short_code"),
+	)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List godoc
+//
+//	@Summary	list urls
+//	@Tags		url
+//	@Security	BearerAuth
+//	@Produce	json
+//	@Param		cursor			query		string	false	"cursor from the previous page"
+//	@Param		limit			query		int		false	"page size"	default(20)	minimum(1)	maximum(100)
+//	@Param		created_from	query		string	false	"created at or after (RFC 3339)"
+//	@Param		created_to		query		string	false	"created before (RFC 3339)"
+//	@Param		q				query		string	false	"substring of the original url"
+//	@Success	200				{object}	response.Ok{data=response.Page{items=[]model.URL}}
+//	@Failure	400				{object}	response.Fail
+//	@Failure	401				{object}	response.Fail
+//	@Failure	500				{object}	response.Fail
+//	@Failure	503				{object}	response.Fail
+//	@Router		/urls [get].
+func (u *URL) List(w http.ResponseWriter, r *http.Request) {
+	req := request.ListURLs{Limit: defaultListLimit}
+	err := helper.ParseQuery(&req, r.URL.Query())
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	urls, nextCursor, err := u.urlService.List(
+		r.Context(),
+		model.URLFilter{
+			CreatedFrom: req.CreatedFrom,
+			CreatedTo:   req.CreatedTo,
+			Search:      req.Search,
+			Limit:       req.Limit,
+		},
+		req.Cursor,
+	)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	helper.Ok(w, http.StatusOK, response.NewPage(urls, nextCursor))
+}
+
+// Export godoc
+//
+//	@Summary		export urls
+//	@Description	Streams every link visible to the caller, newest first, as CSV (with a header row) or JSON lines with the fields short_code, original_url, expires_at, owner_id and created_at.
+//	@Tags			url
+//	@Security		BearerAuth
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Param			format			query		string	false	"file format"	Enums(csv, jsonl)	default(csv)
+//	@Param			created_from	query		string	false	"created at or after (RFC 3339)"
+//	@Param			created_to		query		string	false	"created before (RFC 3339)"
+//	@Param			q				query		string	false	"substring of the original url"
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	response.Fail
+//	@Failure		401				{object}	response.Fail
+//	@Failure		500				{object}	response.Fail
+//	@Failure		503				{object}	response.Fail
+//	@Router			/urls/export [get].
+func (u *URL) Export(w http.ResponseWriter, r *http.Request) {
+	req := request.ExportURLs{Format: linkfile.FormatCSV}
+	err := helper.ParseQuery(&req, r.URL.Query())
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	var writer *linkfile.Writer
+	start := func() error {
+		w.Header().Set("Content-Type", linkfile.ContentType(req.Format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="urls.%s"`, req.Format))
+		w.WriteHeader(http.StatusOK)
+		writer, err = linkfile.NewWriter(w, req.Format)
+		return err
+	}
+
+	err = u.urlService.Export(
+		r.Context(),
+		model.URLFilter{
+			CreatedFrom: req.CreatedFrom,
+			CreatedTo:   req.CreatedTo,
+			Search:      req.Search,
+		},
+		func(urls []model.URL) error {
+			_ = rc.SetWriteDeadline(time.Now().Add(transferIdleTimeout))
+			if writer == nil {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			for _, url := range urls {
+				if err := writer.Write(toRecord(url)); err != nil {
+					return err
+				}
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			return rc.Flush()
+		},
+	)
+	if err != nil && writer == nil {
+		helper.Fail(w, err)
+		return
+	}
+	if err != nil {
+		helper.Abort(err)
+	}
+
+	if writer == nil {
+		if err := start(); err != nil {
+			helper.Abort(err)
+		}
+		if err := writer.Flush(); err != nil {
+			helper.Abort(err)
+		}
+	}
+}
+
+// Import godoc
+//
+//	@Summary		import urls
+//	@Description	Creates the links of a CSV file (with a header row naming at least short_code and original_url) or of JSON lines, keeping their short codes; exports of GET /urls/export can be imported as they are. Rows that are invalid or whose short code is taken are reported and skipped. owner_id is honoured for the admin only.
+//	@Tags			url
+//	@Security		BearerAuth
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			format	query		string	false	"file format"	Enums(csv, jsonl)	default(csv)
+//	@Param			input	body		string	true	"links"
+//	@Success		200		{object}	response.Ok{data=response.ImportReport}
+//	@Failure		400		{object}	response.Fail
+//	@Failure		401		{object}	response.Fail
+//	@Failure		500		{object}	response.Fail
+//	@Failure		503		{object}	response.Fail
+//	@Router			/urls/import [post].
+func (u *URL) Import(w http.ResponseWriter, r *http.Request) {
+	req := request.ImportURLs{Format: linkfile.FormatCSV}
+	err := helper.ParseQuery(&req, r.URL.Query())
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	report, err := u.urlService.Import(
+		r.Context(),
+		&idleReader{reader: r.Body, rc: rc},
+		req.Format,
+	)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	resp := response.ImportReport{
+		Imported: report.Imported,
+		Failed:   make([]response.ImportFailure, len(report.Failed)),
+	}
+	for i, failure := range report.Failed {
+		status, fail := helper.Failure(failure.Err)
+		resp.Failed[i] = response.ImportFailure{
+			Line:      failure.Line,
+			ShortCode: failure.ShortCode,
+			Status:    status,
+			Error:     fail,
+		}
+	}
+
+	_ = rc.SetWriteDeadline(time.Now().Add(transferIdleTimeout))
+	helper.Ok(w, http.StatusOK, resp)
+}
+
+// Stats godoc
+//
+//	@Summary	get url click stats
+//	@Tags		url
+//	@Security	BearerAuth
+//	@Produce	json
+//	@Param		short_code	path		string	true	"short code"
+//	@Param		from		query		string	false	"range start (RFC 3339)"
+//	@Param		to			query		string	false	"range end (RFC 3339), defaults to now"
+//	@Param		bucket		query		string	false	"series bucket"	Enums(minute, hour, day)	default(day)
+//	@Success	200			{object}	response.Ok{data=model.ClickStats}
+//	@Failure	400			{object}	response.Fail
+//	@Failure	401			{object}	response.Fail
+//	@Failure	403			{object}	response.Fail
+//	@Failure	404			{object}	response.Fail
+//	@Failure	500			{object}	response.Fail
+//	@Failure	503			{object}	response.Fail
+//	@Router		/urls/{short_code}/stats [get].
+func (u *URL) Stats(w http.ResponseWriter, r *http.Request) {
+	req := request.URLStats{Bucket: defaultStatsBucket}
+	err := helper.ParseQuery(&req, r.URL.Query())
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	stats, err := u.clickService.Stats(
+		r.Context(),
+		r.PathValue("short_code"),
+		req.From,
+		req.To,
+		req.Bucket,
+	)
+	if err != nil {
+		helper.Fail(w, err)
+		return
+	}
+
+	helper.Ok(w, http.StatusOK, stats)
+}
+
+func toCreateURL(req request.CreateURL) model.CreateURL {
+	return model.CreateURL{
+		OriginalURL:   req.OriginalURL,
+		Alias:         req.Alias,
+		Strategy:      req.Strategy,
+		ExpiresAt:     req.ExpiresAt,
+		ExpiresIn:     time.Duration(req.ExpiresIn),
+		ReuseExisting: req.ReuseExisting,
+	}
+}
+
+// createdStatus answers 200 instead of 201 when an existing link was
+// reused.
+func createdStatus(created bool) int {
+	if created {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+func toRecord(url model.URL) linkfile.Record {
+	record := linkfile.Record{
+		ShortCode:   url.ShortCode,
+		OriginalURL: url.OriginalURL,
+		ExpiresAt:   url.ExpiresAt,
+		CreatedAt:   &url.CreatedAt,
+	}
+	if url.OwnerID != nil {
+		record.OwnerID = *url.OwnerID
+	}
+	return record
+}
+
+// idleReader pushes the read deadline back on every read, so a large body
+// is cut off only when it stalls.
+type idleReader struct {
+	reader io.Reader
+	rc     *http.ResponseController
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	_ = r.rc.SetReadDeadline(time.Now().Add(transferIdleTimeout))
+	return r.reader.Read(p)
+}
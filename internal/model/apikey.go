@@ -0,0 +1,43 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+type APIKey struct {
+	ID        int        `db:"id"         json:"id"`
+	OwnerID   string     `db:"owner_id"   json:"owner_id"`
+	Name      string     `db:"name"       json:"name"`
+	Prefix    string     `db:"prefix"     json:"prefix"`
+	KeyHash   string     `db:"key_hash"   json:"-"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// MintedAPIKey is returned once, when the key is created; only the hash of
+// Secret is stored.
+type MintedAPIKey struct {
+	APIKey
+
+	Secret string `json:"secret"`
+}
+
+// Principal is the authenticated caller. The admin principal comes from the
+// configured admin key and is not bound to an owner.
+type Principal struct {
+	KeyID   int
+	OwnerID string
+	Admin   bool
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
@@ -0,0 +1,8 @@
+package model
+
+// CacheStats counts the lookups of a cache since startup.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Size   int    `json:"size"`
+}
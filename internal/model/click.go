@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+const (
+	GranularityMinute = "minute"
+	GranularityHour   = "hour"
+	GranularityDay    = "day"
+
+	DimensionReferrer = "referrer"
+	DimensionCountry  = "country"
+	DimensionBrowser  = "browser"
+	DimensionOS       = "os"
+)
+
+type Click struct {
+	ShortCode      string    `db:"short_code"`
+	ClickedAt      time.Time `db:"clicked_at"`
+	Referrer       string    `db:"referrer"`
+	UserAgent      string    `db:"user_agent"`
+	IP             string    `db:"ip"`
+	AcceptLanguage string    `db:"accept_language"`
+	Country        string    `db:"country"`
+	Browser        string    `db:"browser"`
+	OS             string    `db:"os"`
+}
+
+// ClickStats covers From to To, except UniqueVisitors and the top values:
+// those are rolled up per UTC day and cover BreakdownFrom to BreakdownTo,
+// the range widened to whole days. UniqueVisitors is an estimate, within
+// about 2% once it runs into the thousands.
+type ClickStats struct {
+	ShortCode      string       `json:"short_code"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	BreakdownFrom  time.Time    `json:"breakdown_from"`
+	BreakdownTo    time.Time    `json:"breakdown_to"`
+	Bucket         string       `json:"bucket"`
+	TotalClicks    int64        `json:"total_clicks"`
+	UniqueVisitors int64        `json:"unique_visitors"`
+	Series         []ClickPoint `json:"series"`
+	TopReferrers   []ClickCount `json:"top_referrers"`
+	TopCountries   []ClickCount `json:"top_countries"`
+	TopBrowsers    []ClickCount `json:"top_browsers"`
+	TopOSes        []ClickCount `json:"top_oses"`
+}
+
+type ClickPoint struct {
+	Time   time.Time `db:"bucket_start" json:"time"`
+	Clicks int64     `db:"clicks"       json:"clicks"`
+}
+
+type ClickCount struct {
+	Value  string `db:"value"  json:"value"`
+	Clicks int64  `db:"clicks" json:"clicks"`
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrGone          = errors.New("gone")
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrForbidden     = errors.New("forbidden")
+	ErrInvalidInput  = errors.New("invalid input")
+	ErrUnprocessable = errors.New("unprocessable")
+	ErrTooLarge      = errors.New("too large")
+	ErrRateLimited   = errors.New("rate limit exceeded")
+	ErrUnavailable   = errors.New("service unavailable")
+)
+
+// Error attaches a client-facing message to one of the sentinel errors above.
+type Error struct {
+	Err     error
+	Message string
+}
+
+func NewError(err error, message string) *Error {
+	return &Error{
+		Err:     err,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// DefaultRetryAfter is the retry hint of an ErrUnavailable error that
+// doesn't carry its own.
+const DefaultRetryAfter = 5 * time.Second
+
+// RetryError tells the client when a temporary failure is worth retrying.
+type RetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func NewRetryError(err error, retryAfter time.Duration) *RetryError {
+	return &RetryError{
+		Err:        err,
+		RetryAfter: retryAfter,
+	}
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns when err is worth retrying: the hint of a RetryError,
+// or DefaultRetryAfter for any other ErrUnavailable error.
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		return retryErr.RetryAfter, true
+	}
+	if errors.Is(err, ErrUnavailable) {
+		return DefaultRetryAfter, true
+	}
+	return 0, false
+}
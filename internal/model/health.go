@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// Health is the state of the instance: degraded while the database is cut
+// off by its circuit breaker and links are served from the cache only.
+type Health struct {
+	Status   string       `json:"status"`
+	Database BreakerStats `json:"database"`
+	Cache    CacheStats   `json:"cache"`
+}
+
+// BreakerStats is the state of a circuit breaker: closed, open or
+// half_open, with the failures in a row that led to it.
+type BreakerStats struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+}
@@ -0,0 +1,11 @@
+package model
+
+// IdempotencyRecord is what is kept for an Idempotency-Key: the fingerprint
+// of the first request and, once it finished, its response for replays.
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Done        bool   `json:"done"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
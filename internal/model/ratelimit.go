@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type RateLimit struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
@@ -3,9 +3,69 @@ package model
 import "time"
 
 type URL struct {
-	ID          int       `db:"id"           json:"id"`
-	ShortCode   string    `db:"short_code"   json:"short_code"`
-	OriginalURL string    `db:"original_url" json:"original_url"`
-	CreatedAt   time.Time `db:"created_at"   json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"   json:"updated_at"`
+	ID              int        `db:"id"                json:"id"`
+	ShortCode       string     `db:"short_code"        json:"short_code"`
+	OriginalURL     string     `db:"original_url"      json:"original_url"`
+	CanonicalURL    string     `db:"canonical_url"     json:"canonical_url"`
+	OriginalURLHash *string    `db:"original_url_hash" json:"-"`
+	ExpiresAt       *time.Time `db:"expires_at"        json:"expires_at,omitempty"`
+	OwnerID         *string    `db:"owner_id"          json:"owner_id,omitempty"`
+	Counter         *int       `db:"counter"           json:"-"`
+	CreatedAt       time.Time  `db:"created_at"        json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"        json:"updated_at"`
+}
+
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
+}
+
+// CreateURL is what a caller asks for when creating a link.
+type CreateURL struct {
+	OriginalURL string
+	Alias       string
+	Strategy    string
+	ExpiresAt   *time.Time
+	ExpiresIn   time.Duration
+	// ReuseExisting returns the caller's live link to the same destination,
+	// if there is one, instead of creating another.
+	ReuseExisting bool
+}
+
+// CreateURLResult is the outcome of one item of a batch create: the link
+// and whether it was created, as returned by a single create, or Err.
+type CreateURLResult struct {
+	URL     *URL
+	Created bool
+	Err     error
+}
+
+// ImportURL is a link brought over from another environment or shortener
+// with its short code.
+type ImportURL struct {
+	ShortCode   string
+	OriginalURL string
+	ExpiresAt   *time.Time
+	OwnerID     *string
+}
+
+// ImportReport sums up an import: how many links were created and the rows
+// that were not, by the line they start on.
+type ImportReport struct {
+	Imported int
+	Failed   []ImportFailure
+}
+
+type ImportFailure struct {
+	Line      int
+	ShortCode string
+	Err       error
+}
+
+type URLFilter struct {
+	OwnerID     *string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Search      string
+	AfterID     int
+	Limit       int
 }
@@ -0,0 +1,134 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const regexPrefix = "regex:"
+
+// Blocklist holds destination rules loaded from a text file with one rule
+// per line: a domain, which also blocks its subdomains, or a regular
+// expression matched against the whole url when prefixed with "regex:".
+// Blank lines and lines starting with "#" are ignored. An empty path is an
+// empty blocklist.
+type Blocklist struct {
+	path string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	domains []string
+	regexps []*regexp.Regexp
+}
+
+func NewBlocklist(path string) *Blocklist {
+	return &Blocklist{path: path}
+}
+
+func MustNewBlocklist(path string) *Blocklist {
+	b := NewBlocklist(path)
+	if _, err := b.Reload(); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Match returns the rule that blocks host or rawURL.
+func (b *Blocklist) Match(host, rawURL string) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, domain := range b.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return domain, true
+		}
+	}
+	for _, re := range b.regexps {
+		if re.MatchString(rawURL) {
+			return regexPrefix + re.String(), true
+		}
+	}
+	return "", false
+}
+
+// Reload reads the file again if it changed since the last load and reports
+// whether it did. On error the previous rules stay in place.
+func (b *Blocklist) Reload() (bool, error) {
+	const op = "repository.file.Blocklist.Reload"
+
+	if b.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	b.mu.RLock()
+	unchanged := info.ModTime().Equal(b.modTime)
+	b.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	domains, regexps, err := b.load()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	b.mu.Lock()
+	b.modTime = info.ModTime()
+	b.domains = domains
+	b.regexps = regexps
+	b.mu.Unlock()
+
+	return true, nil
+}
+
+func (b *Blocklist) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.domains) + len(b.regexps)
+}
+
+func (b *Blocklist) load() ([]string, []*regexp.Regexp, error) {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var (
+		domains []string
+		regexps []*regexp.Regexp
+	)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		rule := strings.TrimSpace(scanner.Text())
+		switch {
+		case rule == "" || strings.HasPrefix(rule, "#"):
+			continue
+		case strings.HasPrefix(rule, regexPrefix):
+			re, err := regexp.Compile(strings.TrimPrefix(rule, regexPrefix))
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			regexps = append(regexps, re)
+		default:
+			domains = append(domains, strings.TrimSuffix(strings.ToLower(rule), "."))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return domains, regexps, nil
+}
@@ -0,0 +1,91 @@
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"url_shortener/internal/repository/file"
+)
+
+func writeRules(t *testing.T, path, rules string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Reload goes by the modification time, which may not tick between
+	// writes.
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlocklistMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeRules(t, path, `
+# phishing
+evil.com
+Tracker.Example.
+regex:^https?://[^/]+/phish
+`, time.Now())
+	blocklist := file.MustNewBlocklist(path)
+
+	tests := []struct {
+		host   string
+		rawURL string
+		rule   string
+	}{
+		{"evil.com", "http://evil.com/", "evil.com"},
+		{"login.evil.com", "http://login.evil.com/", "evil.com"},
+		{"EVIL.Com", "http://EVIL.Com/", "evil.com"},
+		{"evil.com.", "http://evil.com./", "evil.com"},
+		{"tracker.example", "http://tracker.example/", "tracker.example"},
+		{"a.b.TRACKER.example.", "http://a.b.tracker.example./", "tracker.example"},
+		{"notevil.com", "http://notevil.com/", ""},
+		{"evil.com.example.net", "http://evil.com.example.net/", ""},
+		{"evil.co", "http://evil.co/", ""},
+		{"good.com", "https://good.com/phish/login", "regex:^https?://[^/]+/phish"},
+		{"good.com", "https://good.com/ok?next=/phish", ""},
+	}
+	for _, tt := range tests {
+		rule, ok := blocklist.Match(tt.host, tt.rawURL)
+		if ok != (tt.rule != "") || rule != tt.rule {
+			t.Fatalf("Match(%s, %s) = %q, %v, want %q", tt.host, tt.rawURL, rule, ok, tt.rule)
+		}
+	}
+}
+
+func TestBlocklistReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	start := time.Now().Add(-time.Hour)
+	writeRules(t, path, "evil.com\n", start)
+	blocklist := file.MustNewBlocklist(path)
+
+	if reloaded, err := blocklist.Reload(); err != nil || reloaded {
+		t.Fatalf("Reload() of an unchanged file = %v, %v", reloaded, err)
+	}
+
+	writeRules(t, path, "evil.com\nbad.org\n", start.Add(time.Minute))
+	if reloaded, err := blocklist.Reload(); err != nil || !reloaded {
+		t.Fatalf("Reload() of a changed file = %v, %v", reloaded, err)
+	}
+	if _, ok := blocklist.Match("bad.org", "http://bad.org/"); !ok {
+		t.Fatal("rule added by the reload doesn't match")
+	}
+
+	// A broken file keeps the rules in place.
+	writeRules(t, path, "regex:(\n", start.Add(2*time.Minute))
+	if _, err := blocklist.Reload(); err == nil {
+		t.Fatal("Reload() accepted an invalid regex")
+	}
+	if blocklist.Len() != 2 {
+		t.Fatalf("Len() = %d after a failed reload, want 2", blocklist.Len())
+	}
+}
+
+func TestEmptyBlocklist(t *testing.T) {
+	blocklist := file.MustNewBlocklist("")
+	if _, ok := blocklist.Match("evil.com", "http://evil.com/"); ok {
+		t.Fatal("empty blocklist matched")
+	}
+}
@@ -6,6 +6,26 @@ import (
 )
 
 type URL interface {
-	Create(ctx context.Context, shortCode, originalURL string) (*model.URL, error)
-	GetOriginalURLByShortCode(ctx context.Context, shortCode string) (string, error)
+	Create(ctx context.Context, url *model.URL) (*model.URL, error)
+	CreateBatch(ctx context.Context, urls []*model.URL) ([]model.URL, error)
+	GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error)
+	GetLatestByCanonicalURL(ctx context.Context, ownerID *string, canonicalURL string) (*model.URL, error)
+	Update(ctx context.Context, shortCode, originalURL, canonicalURL string) (*model.URL, error)
+	Delete(ctx context.Context, shortCode string) error
+	List(ctx context.Context, filter model.URLFilter) ([]model.URL, error)
+}
+
+// ShortCodeSource pages through the short codes of every link.
+type ShortCodeSource interface {
+	ListShortCodes(ctx context.Context, afterID, limit int) ([]model.URL, error)
+}
+
+// CounterRange reserves blocks of counter values in one round trip.
+type CounterRange interface {
+	IncrBy(ctx context.Context, n int) (int, error)
+	Reseed(ctx context.Context, floor int) error
+}
+
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, cost int) (*model.RateLimit, error)
 }
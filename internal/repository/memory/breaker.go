@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"url_shortener/internal/model"
+	"url_shortener/internal/repository"
+	"url_shortener/internal/utils/breaker"
+)
+
+// URLBreaker guards the repository.URL behind it, usually the Postgres one,
+// with a circuit breaker. Once it trips every call fails fast with
+// model.ErrUnavailable and a retry hint, so the caches in front keep
+// serving what they hold while writes and misses are turned away until a
+// probe gets through again. Not found and conflict are answers, not
+// failures, and calls canceled by the caller don't count.
+type URLBreaker struct {
+	breaker       *breaker.Breaker
+	urlRepository repository.URL
+}
+
+func NewURLBreaker(
+	breaker *breaker.Breaker,
+	urlRepository repository.URL,
+) *URLBreaker {
+	return &URLBreaker{
+		breaker:       breaker,
+		urlRepository: urlRepository,
+	}
+}
+
+func (u *URLBreaker) Create(ctx context.Context, url *model.URL) (*model.URL, error) {
+	const op = "repository.memory.URLBreaker.Create"
+
+	if err := u.allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, err := u.urlRepository.Create(ctx, url)
+	u.record(err)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (u *URLBreaker) CreateBatch(ctx context.Context, urls []*model.URL) ([]model.URL, error) {
+	const op = "repository.memory.URLBreaker.CreateBatch"
+
+	if err := u.allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err := u.urlRepository.CreateBatch(ctx, urls)
+	u.record(err)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+func (u *URLBreaker) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	const op = "repository.memory.URLBreaker.GetByShortCode"
+
+	if err := u.allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, err := u.urlRepository.GetByShortCode(ctx, shortCode)
+	u.record(err)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (u *URLBreaker) GetLatestByCanonicalURL(ctx context.Context, ownerID *string, canonicalURL string) (*model.URL, error) {
+	const op = "repository.memory.URLBreaker.GetLatestByCanonicalURL"
+
+	if err := u.allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, err := u.urlRepository.GetLatestByCanonicalURL(ctx, ownerID, canonicalURL)
+	u.record(err)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (u *URLBreaker) Update(ctx context.Context, shortCode, originalURL, canonicalURL string) (*model.URL, error) {
+	const op = "repository.memory.URLBreaker.Update"
+
+	if err := u.allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, err := u.urlRepository.Update(ctx, shortCode, originalURL, canonicalURL)
+	u.record(err)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (u *URLBreaker) Delete(ctx context.Context, shortCode string) error {
+	const op = "repository.memory.URLBreaker.Delete"
+
+	if err := u.allow(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := u.urlRepository.Delete(ctx, shortCode)
+	u.record(err)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (u *URLBreaker) List(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	const op = "repository.memory.URLBreaker.List"
+
+	if err := u.allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	urls, err := u.urlRepository.List(ctx, filter)
+	u.record(err)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+func (u *URLBreaker) Stats() model.BreakerStats {
+	stats := u.breaker.Stats()
+	result := model.BreakerStats{
+		State:    string(stats.State),
+		Failures: stats.Failures,
+	}
+	if !stats.OpenedAt.IsZero() {
+		result.OpenedAt = &stats.OpenedAt
+	}
+	return result
+}
+
+func (u *URLBreaker) allow() error {
+	if retryAfter, ok := u.breaker.Allow(); !ok {
+		return model.NewRetryError(model.ErrUnavailable, retryAfter)
+	}
+	return nil
+}
+
+func (u *URLBreaker) record(err error) {
+	if errors.Is(err, context.Canceled) {
+		u.breaker.Abandon()
+		return
+	}
+	failed := err != nil &&
+		!errors.Is(err, model.ErrNotFound) &&
+		!errors.Is(err, model.ErrConflict)
+	u.breaker.Record(failed)
+}
@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"url_shortener/internal/repository"
+)
+
+const (
+	// refillDivisor starts leasing the next block once a quarter of the
+	// current one is left.
+	refillDivisor = 4
+	refillTimeout = 5 * time.Second
+)
+
+// Counter hands out counter values from blocks leased with a single
+// IncrBy, so most calls never leave the process. The next block is leased
+// in the background before the current one runs out. Values of a block
+// that is never used up, for example on restart, are skipped, never
+// reused.
+type Counter struct {
+	blockSize    int
+	logger       *slog.Logger
+	counterRange repository.CounterRange
+
+	mu         sync.Mutex
+	next       int
+	end        int
+	spare      *block
+	refilling  bool
+	generation int
+}
+
+type block struct {
+	start int
+	end   int
+}
+
+func NewCounter(
+	blockSize int,
+	logger *slog.Logger,
+	counterRange repository.CounterRange,
+) *Counter {
+	return &Counter{
+		blockSize:    max(blockSize, 1),
+		logger:       logger,
+		counterRange: counterRange,
+		next:         1,
+	}
+}
+
+func (c *Counter) Incr(ctx context.Context) (int, error) {
+	const op = "repository.memory.Counter.Incr"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next > c.end {
+		if c.spare != nil {
+			c.next, c.end = c.spare.start, c.spare.end
+			c.spare = nil
+		} else {
+			b, err := c.lease(ctx)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %w", op, err)
+			}
+			c.next, c.end = b.start, b.end
+		}
+	}
+
+	num := c.next
+	c.next++
+	c.maybeRefill()
+
+	return num, nil
+}
+
+// IncrBy reserves n consecutive values and returns the last one. They come
+// from the current block when it has enough left, otherwise they are leased
+// on their own in a single round trip and the block is kept for Incr.
+func (c *Counter) IncrBy(ctx context.Context, n int) (int, error) {
+	const op = "repository.memory.Counter.IncrBy"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.end-c.next+1 >= n {
+		c.next += n
+		c.maybeRefill()
+		return c.next - 1, nil
+	}
+
+	end, err := c.counterRange.IncrBy(ctx, n)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return end, nil
+}
+
+// Reseed drops the leased blocks, which may lie below floor, so the next
+// Incr leases a fresh block past it.
+func (c *Counter) Reseed(ctx context.Context, floor int) error {
+	const op = "repository.memory.Counter.Reseed"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.counterRange.Reseed(ctx, floor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	c.next, c.end = 1, 0
+	c.spare = nil
+	c.generation++
+
+	return nil
+}
+
+// maybeRefill starts leasing the spare block once the current one runs
+// low. It must be called with mu held.
+func (c *Counter) maybeRefill() {
+	if c.end-c.next < c.blockSize/refillDivisor && c.spare == nil && !c.refilling {
+		c.refilling = true
+		go c.refill()
+	}
+}
+
+// refill leases the spare block; on failure Incr falls back to leasing
+// synchronously once the current block is used up.
+func (c *Counter) refill() {
+	ctx, cancel := context.WithTimeout(context.Background(), refillTimeout)
+	defer cancel()
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	b, err := c.lease(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.refilling = false
+	if generation != c.generation {
+		return
+	}
+	if err != nil {
+		c.logger.WarnContext(ctx, "failed to lease counter block",
+			slog.Int("block_size", c.blockSize),
+			slog.Any("error", err),
+		)
+		return
+	}
+	c.spare = b
+}
+
+func (c *Counter) lease(ctx context.Context) (*block, error) {
+	end, err := c.counterRange.IncrBy(ctx, c.blockSize)
+	if err != nil {
+		return nil, err
+	}
+	return &block{start: end - c.blockSize + 1, end: end}, nil
+}
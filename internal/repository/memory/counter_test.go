@@ -0,0 +1,213 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+	"url_shortener/internal/repository/memory"
+)
+
+// fakeRange is a CounterRange whose IncrBy may be held between reserving
+// the values and returning them, to line up a refill with a Reseed.
+type fakeRange struct {
+	mu    sync.Mutex
+	value int
+	calls int
+	err   error
+	hold  chan struct{}
+	held  chan struct{}
+}
+
+func (f *fakeRange) IncrBy(_ context.Context, n int) (int, error) {
+	f.mu.Lock()
+	if f.err != nil {
+		f.mu.Unlock()
+		return 0, f.err
+	}
+	f.value += n
+	f.calls++
+	end, hold, held := f.value, f.hold, f.held
+	f.hold = nil
+	f.mu.Unlock()
+
+	if hold != nil {
+		close(held)
+		<-hold
+	}
+	return end, nil
+}
+
+func (f *fakeRange) Reseed(_ context.Context, floor int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value = max(f.value, floor)
+	return nil
+}
+
+func (f *fakeRange) leases() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func newCounter(blockSize int, counterRange *fakeRange) *memory.Counter {
+	return memory.NewCounter(blockSize, slog.New(slog.NewTextHandler(io.Discard, nil)), counterRange)
+}
+
+// waitFor polls cond, since refills run in the background.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("condition not met within 1s")
+}
+
+func TestCounterRefillsInBackground(t *testing.T) {
+	counterRange := &fakeRange{}
+	counter := newCounter(8, counterRange)
+	ctx := context.Background()
+
+	// Leaves 2 of 8, below a quarter, so the spare block is leased.
+	for want := 1; want <= 6; want++ {
+		got, err := counter.Incr(ctx)
+		if err != nil || got != want {
+			t.Fatalf("Incr() = %d, %v, want %d", got, err, want)
+		}
+	}
+	waitFor(t, func() bool { return counterRange.leases() == 2 })
+
+	counterRange.mu.Lock()
+	counterRange.err = errors.New("unavailable")
+	counterRange.mu.Unlock()
+
+	// The rest of the block and the spare need no round trip.
+	for want := 7; want <= 16; want++ {
+		got, err := counter.Incr(ctx)
+		if err != nil || got != want {
+			t.Fatalf("Incr() = %d, %v, want %d", got, err, want)
+		}
+	}
+	if _, err := counter.Incr(ctx); err == nil {
+		t.Fatal("Incr() succeeded with no values left and the range failing")
+	}
+}
+
+func TestCounterIncrBy(t *testing.T) {
+	counterRange := &fakeRange{}
+	counter := newCounter(100, counterRange)
+	ctx := context.Background()
+
+	if got, _ := counter.Incr(ctx); got != 1 {
+		t.Fatalf("Incr() = %d, want 1", got)
+	}
+	// Taken from the current block.
+	if got, _ := counter.IncrBy(ctx, 10); got != 11 {
+		t.Fatalf("IncrBy(10) = %d, want 11", got)
+	}
+	// Too many for the block, leased on their own.
+	if got, _ := counter.IncrBy(ctx, 500); got <= 100 {
+		t.Fatalf("IncrBy(500) = %d, want past the block", got)
+	}
+	// The block is kept for Incr.
+	if got, _ := counter.Incr(ctx); got != 12 {
+		t.Fatalf("Incr() = %d, want 12", got)
+	}
+}
+
+func TestCounterValuesAreUnique(t *testing.T) {
+	counterRange := &fakeRange{}
+	counter := newCounter(16, counterRange)
+	ctx := context.Background()
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[int]struct{})
+		wg   sync.WaitGroup
+	)
+	record := func(values ...int) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, v := range values {
+			if _, ok := seen[v]; ok {
+				t.Errorf("value %d handed out twice", v)
+			}
+			seen[v] = struct{}{}
+		}
+	}
+
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				if g%2 == 0 && i%10 == 0 {
+					last, err := counter.IncrBy(ctx, 5)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					record(last-4, last-3, last-2, last-1, last)
+					continue
+				}
+				num, err := counter.Incr(ctx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				record(num)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// A refill that was in flight when the counter was reseeded leased a block
+// below the floor, which must be dropped.
+func TestCounterReseedDropsInflightRefill(t *testing.T) {
+	counterRange := &fakeRange{}
+	counter := newCounter(4, counterRange)
+	ctx := context.Background()
+
+	if _, err := counter.Incr(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	counterRange.mu.Lock()
+	counterRange.hold = make(chan struct{})
+	counterRange.held = make(chan struct{})
+	hold, held := counterRange.hold, counterRange.held
+	counterRange.mu.Unlock()
+
+	// Leaves 1 of 4 and starts the refill, which reserves 5..8 and waits.
+	for range 2 {
+		if _, err := counter.Incr(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-held
+
+	if err := counter.Reseed(ctx, 1000); err != nil {
+		t.Fatal(err)
+	}
+	close(hold)
+	// Let the refill finish before Incr needs a block.
+	time.Sleep(10 * time.Millisecond)
+
+	for range 8 {
+		num, err := counter.Incr(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if num <= 1000 {
+			t.Fatalf("Incr() = %d after reseeding to 1000", num)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"url_shortener/internal/model"
+	"url_shortener/internal/repository"
+	"url_shortener/internal/utils/bloom"
+)
+
+const filterRebuildBatchSize = 10000
+
+// ShortCodeFilter answers lookups of codes that were never created without
+// reaching the repository.URL behind it, using a Bloom filter of every
+// existing code. The filter is built from the shortCodeSource by Rebuild,
+// until then and whenever Purge marks it stale every lookup passes through.
+// Codes created through this instance are added right away, those created
+// elsewhere must be passed to Invalidate; one that never is, because the
+// announcement was lost, is missed until the next Rebuild, so rebuilds have
+// to run periodically. A capacity of zero disables the filter.
+type ShortCodeFilter struct {
+	capacity          int
+	falsePositiveRate float64
+	urlRepository     repository.URL
+	shortCodeSource   repository.ShortCodeSource
+
+	ready atomic.Pointer[bloom.Filter]
+	stale chan struct{}
+
+	mu       sync.Mutex
+	building *bloom.Filter
+}
+
+func NewShortCodeFilter(
+	capacity int,
+	falsePositiveRate float64,
+	urlRepository repository.URL,
+	shortCodeSource repository.ShortCodeSource,
+) *ShortCodeFilter {
+	f := &ShortCodeFilter{
+		capacity:          capacity,
+		falsePositiveRate: falsePositiveRate,
+		urlRepository:     urlRepository,
+		shortCodeSource:   shortCodeSource,
+		stale:             make(chan struct{}, 1),
+	}
+	if capacity > 0 {
+		f.stale <- struct{}{}
+	}
+	return f
+}
+
+func (f *ShortCodeFilter) Create(ctx context.Context, url *model.URL) (*model.URL, error) {
+	const op = "repository.memory.ShortCodeFilter.Create"
+
+	url, err := f.urlRepository.Create(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	f.Invalidate(url.ShortCode)
+
+	return url, nil
+}
+
+func (f *ShortCodeFilter) CreateBatch(ctx context.Context, urls []*model.URL) ([]model.URL, error) {
+	const op = "repository.memory.ShortCodeFilter.CreateBatch"
+
+	created, err := f.urlRepository.CreateBatch(ctx, urls)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range created {
+		f.Invalidate(created[i].ShortCode)
+	}
+
+	return created, nil
+}
+
+func (f *ShortCodeFilter) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	const op = "repository.memory.ShortCodeFilter.GetByShortCode"
+
+	if filter := f.ready.Load(); filter != nil && !filter.MayContain(shortCode) {
+		return nil, fmt.Errorf("%s: %w", op, model.ErrNotFound)
+	}
+
+	url, err := f.urlRepository.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (f *ShortCodeFilter) GetLatestByCanonicalURL(ctx context.Context, ownerID *string, canonicalURL string) (*model.URL, error) {
+	const op = "repository.memory.ShortCodeFilter.GetLatestByCanonicalURL"
+
+	url, err := f.urlRepository.GetLatestByCanonicalURL(ctx, ownerID, canonicalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (f *ShortCodeFilter) Update(ctx context.Context, shortCode, originalURL, canonicalURL string) (*model.URL, error) {
+	const op = "repository.memory.ShortCodeFilter.Update"
+
+	url, err := f.urlRepository.Update(ctx, shortCode, originalURL, canonicalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+// Delete leaves the code in the filter, a Bloom filter can't forget values;
+// lookups of it reach the repository until the next rebuild.
+func (f *ShortCodeFilter) Delete(ctx context.Context, shortCode string) error {
+	const op = "repository.memory.ShortCodeFilter.Delete"
+
+	if err := f.urlRepository.Delete(ctx, shortCode); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (f *ShortCodeFilter) List(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	const op = "repository.memory.ShortCodeFilter.List"
+
+	urls, err := f.urlRepository.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// Invalidate adds shortCode to the filter, for links created elsewhere.
+func (f *ShortCodeFilter) Invalidate(shortCode string) {
+	if f.capacity <= 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if filter := f.ready.Load(); filter != nil {
+		filter.Add(shortCode)
+	}
+	if f.building != nil {
+		f.building.Add(shortCode)
+	}
+}
+
+// Purge stops using the filter until it is rebuilt, for when codes created
+// elsewhere may have been missed. A rebuild in progress is discarded.
+func (f *ShortCodeFilter) Purge() {
+	if f.capacity <= 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ready.Store(nil)
+	f.building = nil
+	select {
+	case f.stale <- struct{}{}:
+	default:
+	}
+}
+
+func (f *ShortCodeFilter) Enabled() bool {
+	return f.capacity > 0
+}
+
+// Stale receives when the filter needs to be rebuilt.
+func (f *ShortCodeFilter) Stale() <-chan struct{} {
+	return f.stale
+}
+
+// Rebuild builds a new filter from every code in the shortCodeSource and
+// puts it in use, returning the number of codes read. Codes added while
+// it runs go into both the old and the new filter; a Purge while it runs
+// fails it and marks the filter stale again.
+func (f *ShortCodeFilter) Rebuild(ctx context.Context) (int, error) {
+	const op = "repository.memory.ShortCodeFilter.Rebuild"
+
+	if f.capacity <= 0 {
+		return 0, nil
+	}
+
+	filter := bloom.New(f.capacity, f.falsePositiveRate)
+	f.mu.Lock()
+	f.building = filter
+	select {
+	case <-f.stale:
+	default:
+	}
+	f.mu.Unlock()
+
+	count := 0
+	afterID := 0
+	for {
+		urls, err := f.shortCodeSource.ListShortCodes(ctx, afterID, filterRebuildBatchSize)
+		if err != nil {
+			f.mu.Lock()
+			if f.building == filter {
+				f.building = nil
+			}
+			f.mu.Unlock()
+			return count, fmt.Errorf("%s: %w", op, err)
+		}
+		for i := range urls {
+			filter.Add(urls[i].ShortCode)
+		}
+		count += len(urls)
+		if len(urls) < filterRebuildBatchSize {
+			break
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.building != filter {
+		return count, fmt.Errorf("%s: purged while rebuilding", op)
+	}
+	f.ready.Store(filter)
+	f.building = nil
+
+	return count, nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+	"url_shortener/internal/model"
+	"url_shortener/internal/utils/lru"
+)
+
+// RateLimiter is a token bucket per key: each bucket holds up to limit
+// tokens and refills at limit tokens per period. A request takes cost
+// tokens; one costing more than limit could never fit and is denied
+// without touching the bucket.
+// Buckets live in an LRU so memory stays bounded by maxKeys; an evicted key
+// simply starts full again.
+type RateLimiter struct {
+	limit  int
+	period time.Duration
+
+	mu      sync.Mutex
+	buckets *lru.Cache[string, *bucket]
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewRateLimiter(
+	limit int,
+	period time.Duration,
+	maxKeys int,
+) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		period:  period,
+		buckets: lru.New[string, *bucket](maxKeys),
+	}
+}
+
+func (r *RateLimiter) Allow(_ context.Context, key string, cost int) (*model.RateLimit, error) {
+	cost = max(cost, 1)
+	if cost > r.limit {
+		return &model.RateLimit{Limit: r.limit}, nil
+	}
+
+	now := time.Now()
+	rate := float64(r.limit) / r.period.Seconds()
+	tokens := float64(cost)
+
+	r.mu.Lock()
+	b, ok := r.buckets.Get(key)
+	if !ok {
+		b = &bucket{tokens: float64(r.limit), updated: now}
+		r.buckets.Add(key, b)
+	}
+
+	b.tokens = min(float64(r.limit), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := model.RateLimit{Limit: r.limit}
+	if b.tokens >= tokens {
+		b.tokens -= tokens
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((tokens - b.tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = secondsToDuration((float64(r.limit) - b.tokens) / rate)
+	r.mu.Unlock()
+
+	return &result, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,102 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url_shortener/internal/repository/memory"
+)
+
+func TestRateLimiterAllowsBurstThenLimits(t *testing.T) {
+	limiter := memory.NewRateLimiter(3, time.Minute, 10)
+	ctx := context.Background()
+
+	for i := range 3 {
+		result, err := limiter.Allow(ctx, "client", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d: allowed %v, remaining %d", i, result.Allowed, result.Remaining)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, "client", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Fatal("request past the burst was allowed")
+	}
+	// One token refills every 20s.
+	if result.RetryAfter <= 19*time.Second || result.RetryAfter > 20*time.Second {
+		t.Fatalf("RetryAfter = %s, want about 20s", result.RetryAfter)
+	}
+
+	other, err := limiter.Allow(ctx, "other", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !other.Allowed {
+		t.Fatal("another client shares the bucket")
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := memory.NewRateLimiter(2, 100*time.Millisecond, 10)
+	ctx := context.Background()
+
+	for range 2 {
+		if result, _ := limiter.Allow(ctx, "client", 1); !result.Allowed {
+			t.Fatal("burst request was limited")
+		}
+	}
+	if result, _ := limiter.Allow(ctx, "client", 1); result.Allowed {
+		t.Fatal("empty bucket allowed a request")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if result, _ := limiter.Allow(ctx, "client", 1); !result.Allowed {
+		t.Fatal("request after a refill was limited")
+	}
+}
+
+func TestRateLimiterCost(t *testing.T) {
+	limiter := memory.NewRateLimiter(10, time.Minute, 10)
+	ctx := context.Background()
+
+	result, _ := limiter.Allow(ctx, "client", 4)
+	if !result.Allowed || result.Remaining != 6 {
+		t.Fatalf("cost 4: allowed %v, remaining %d", result.Allowed, result.Remaining)
+	}
+	if result, _ = limiter.Allow(ctx, "client", 7); result.Allowed {
+		t.Fatal("cost 7 was allowed with 6 tokens left")
+	}
+	if result, _ = limiter.Allow(ctx, "client", 6); !result.Allowed {
+		t.Fatal("cost 6 was limited with 6 tokens left")
+	}
+
+	// A cost above the limit is denied and leaves the bucket full.
+	if result, _ = limiter.Allow(ctx, "fresh", 50); result.Allowed {
+		t.Fatal("cost over limit was allowed")
+	}
+	if result, _ = limiter.Allow(ctx, "fresh", 10); !result.Allowed {
+		t.Fatal("cost over limit took tokens")
+	}
+}
+
+func TestRateLimiterEvictedKeyStartsFull(t *testing.T) {
+	limiter := memory.NewRateLimiter(1, time.Hour, 1)
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "a", 1) //nolint:errcheck // never fails
+	if result, _ := limiter.Allow(ctx, "a", 1); result.Allowed {
+		t.Fatal("empty bucket allowed a request")
+	}
+
+	limiter.Allow(ctx, "b", 1) //nolint:errcheck // evicts "a"
+	if result, _ := limiter.Allow(ctx, "a", 1); !result.Allowed {
+		t.Fatal("evicted key didn't start with a full bucket")
+	}
+}
@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url_shortener/internal/model"
+	"url_shortener/internal/repository"
+	"url_shortener/internal/utils/lru"
+)
+
+// URL keeps recently resolved links in process in front of another
+// repository.URL, usually the Valkey one, so hot links skip the network.
+// Entries live at most ttl: writes through this instance evict them at
+// once, writes through other instances show up within ttl, or sooner when
+// they are passed to Invalidate. A size of zero disables the cache.
+type URL struct {
+	ttl           time.Duration
+	urlRepository repository.URL
+
+	mu    sync.Mutex
+	cache *lru.Cache[string, *cachedURL]
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type cachedURL struct {
+	url     model.URL
+	expires time.Time
+}
+
+func NewURL(
+	size int,
+	ttl time.Duration,
+	urlRepository repository.URL,
+) *URL {
+	u := &URL{
+		ttl:           ttl,
+		urlRepository: urlRepository,
+	}
+	if size > 0 && ttl > 0 {
+		u.cache = lru.New[string, *cachedURL](size)
+	}
+	return u
+}
+
+func (u *URL) Create(ctx context.Context, url *model.URL) (*model.URL, error) {
+	const op = "repository.memory.URL.Create"
+
+	url, err := u.urlRepository.Create(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (u *URL) CreateBatch(ctx context.Context, urls []*model.URL) ([]model.URL, error) {
+	const op = "repository.memory.URL.CreateBatch"
+
+	created, err := u.urlRepository.CreateBatch(ctx, urls)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+func (u *URL) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	const op = "repository.memory.URL.GetByShortCode"
+
+	if url, ok := u.get(shortCode); ok {
+		u.hits.Add(1)
+		return url, nil
+	}
+	if u.cache != nil {
+		u.misses.Add(1)
+	}
+
+	url, err := u.urlRepository.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	u.set(url)
+
+	return url, nil
+}
+
+func (u *URL) GetLatestByCanonicalURL(ctx context.Context, ownerID *string, canonicalURL string) (*model.URL, error) {
+	const op = "repository.memory.URL.GetLatestByCanonicalURL"
+
+	url, err := u.urlRepository.GetLatestByCanonicalURL(ctx, ownerID, canonicalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+// Update and Delete evict the entry both before and after the write, the
+// second time in case a concurrent read cached the old value in between.
+func (u *URL) Update(ctx context.Context, shortCode, originalURL, canonicalURL string) (*model.URL, error) {
+	const op = "repository.memory.URL.Update"
+
+	u.Invalidate(shortCode)
+	defer u.Invalidate(shortCode)
+
+	url, err := u.urlRepository.Update(ctx, shortCode, originalURL, canonicalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (u *URL) Delete(ctx context.Context, shortCode string) error {
+	const op = "repository.memory.URL.Delete"
+
+	u.Invalidate(shortCode)
+	defer u.Invalidate(shortCode)
+
+	if err := u.urlRepository.Delete(ctx, shortCode); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (u *URL) List(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	const op = "repository.memory.URL.List"
+
+	urls, err := u.urlRepository.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// Invalidate evicts the entry of shortCode, for links changed elsewhere.
+func (u *URL) Invalidate(shortCode string) {
+	if u.cache == nil {
+		return
+	}
+	u.mu.Lock()
+	u.cache.Remove(shortCode)
+	u.mu.Unlock()
+}
+
+// Purge evicts every entry.
+func (u *URL) Purge() {
+	if u.cache == nil {
+		return
+	}
+	u.mu.Lock()
+	u.cache.Purge()
+	u.mu.Unlock()
+}
+
+func (u *URL) Stats() model.CacheStats {
+	stats := model.CacheStats{
+		Hits:   u.hits.Load(),
+		Misses: u.misses.Load(),
+	}
+	if u.cache != nil {
+		u.mu.Lock()
+		stats.Size = u.cache.Len()
+		u.mu.Unlock()
+	}
+	return stats
+}
+
+func (u *URL) get(shortCode string) (*model.URL, bool) {
+	if u.cache == nil {
+		return nil, false
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	entry, ok := u.cache.Get(shortCode)
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expires) {
+		u.cache.Remove(shortCode)
+		return nil, false
+	}
+
+	url := entry.url
+	return &url, true
+}
+
+func (u *URL) set(url *model.URL) {
+	if u.cache == nil {
+		return
+	}
+
+	u.mu.Lock()
+	u.cache.Add(url.ShortCode, &cachedURL{url: *url, expires: time.Now().Add(u.ttl)})
+	u.mu.Unlock()
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"url_shortener/internal/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type APIKey struct {
+	db *sqlx.DB
+}
+
+func NewAPIKey(
+	db *sqlx.DB,
+) *APIKey {
+	return &APIKey{db: db}
+}
+
+func (a *APIKey) Create(ctx context.Context, apiKey *model.APIKey) (*model.APIKey, error) {
+	const op = "repository.postgres.APIKey.Create"
+
+	var created model.APIKey
+	err := a.db.GetContext(ctx, &created,
+		`
+			insert into api_keys (owner_id, name, prefix, key_hash)
+			values ($1, $2, $3, $4)
+			returning *
+		`,
+		apiKey.OwnerID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return &created, nil
+}
+
+func (a *APIKey) GetActiveByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	const op = "repository.postgres.APIKey.GetActiveByHash"
+
+	var apiKey model.APIKey
+	err := a.db.GetContext(ctx, &apiKey,
+		`
+			select * from api_keys where key_hash = $1 and revoked_at is null
+		`,
+		keyHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return &apiKey, nil
+}
+
+func (a *APIKey) GetByID(ctx context.Context, id int) (*model.APIKey, error) {
+	const op = "repository.postgres.APIKey.GetByID"
+
+	var apiKey model.APIKey
+	err := a.db.GetContext(ctx, &apiKey,
+		`
+			select * from api_keys where id = $1
+		`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return &apiKey, nil
+}
+
+// List returns the keys of ownerID, or every key when ownerID is nil.
+func (a *APIKey) List(ctx context.Context, ownerID *string) ([]model.APIKey, error) {
+	const op = "repository.postgres.APIKey.List"
+
+	apiKeys := make([]model.APIKey, 0)
+	err := a.db.SelectContext(ctx, &apiKeys,
+		`
+			select * from api_keys
+			where $1::uuid is null or owner_id = $1::uuid
+			order by id
+		`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return apiKeys, nil
+}
+
+func (a *APIKey) Revoke(ctx context.Context, id int) (*model.APIKey, error) {
+	const op = "repository.postgres.APIKey.Revoke"
+
+	var apiKey model.APIKey
+	err := a.db.GetContext(ctx, &apiKey,
+		`
+			update api_keys set revoked_at = coalesce(revoked_at, now())
+			where id = $1
+			returning *
+		`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return &apiKey, nil
+}
@@ -0,0 +1,417 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"url_shortener/internal/model"
+	"url_shortener/internal/utils/hll"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Click struct {
+	db *sqlx.DB
+}
+
+func NewClick(
+	db *sqlx.DB,
+) *Click {
+	return &Click{db: db}
+}
+
+// clickRow is a click resolved to the link it was made on.
+type clickRow struct {
+	model.Click
+
+	URLID int `db:"url_id"`
+}
+
+type seriesRow struct {
+	URLID       int       `db:"url_id"`
+	Granularity string    `db:"granularity"`
+	BucketStart time.Time `db:"bucket_start"`
+	Clicks      int64     `db:"clicks"`
+}
+
+type dimensionRow struct {
+	URLID     int    `db:"url_id"`
+	Day       string `db:"day"`
+	Dimension string `db:"dimension"`
+	Value     string `db:"value"`
+	Clicks    int64  `db:"clicks"`
+}
+
+type visitorKey struct {
+	URLID int
+	Day   string
+}
+
+type visitorSketch struct {
+	visitorKey
+
+	Sketch *hll.Sketch
+}
+
+// CreateBatch stores raw clicks and folds them into the rollup tables read
+// by the stats queries within one transaction. Clicks are stored against
+// the link id, so the rollups go with the link when it is deleted; clicks
+// on a link deleted before the batch is flushed are dropped.
+func (c *Click) CreateBatch(ctx context.Context, clicks []model.Click) error {
+	const op = "repository.postgres.Click.CreateBatch"
+
+	tx, err := c.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	rows, err := resolveClicks(ctx, tx, clicks)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	series, dimensions, visitors := rollup(rows)
+
+	_, err = tx.NamedExecContext(ctx,
+		`
+			insert into clicks (
+				url_id, clicked_at, referrer, user_agent, ip, accept_language, country, browser, os
+			)
+			values (
+				:url_id, :clicked_at, :referrer, :user_agent, :ip, :accept_language, :country, :browser, :os
+			)
+		`,
+		rows,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	_, err = tx.NamedExecContext(ctx,
+		`
+			insert into click_series (url_id, granularity, bucket_start, clicks)
+			values (:url_id, :granularity, :bucket_start, :clicks)
+			on conflict (url_id, granularity, bucket_start)
+			do update set clicks = click_series.clicks + excluded.clicks
+		`,
+		series,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	_, err = tx.NamedExecContext(ctx,
+		`
+			insert into click_dimensions (url_id, day, dimension, value, clicks)
+			values (:url_id, :day, :dimension, :value, :clicks)
+			on conflict (url_id, dimension, day, value)
+			do update set clicks = click_dimensions.clicks + excluded.clicks
+		`,
+		dimensions,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	if err := mergeVisitors(ctx, tx, visitors); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (c *Click) GetSeries(
+	ctx context.Context,
+	urlID int,
+	granularity string,
+	from, to time.Time,
+) ([]model.ClickPoint, error) {
+	const op = "repository.postgres.Click.GetSeries"
+
+	var points []model.ClickPoint
+	err := c.db.SelectContext(ctx, &points,
+		`
+			select bucket_start, clicks from click_series
+			where url_id = $1 and granularity = $2 and bucket_start >= $3 and bucket_start < $4
+			order by bucket_start
+		`,
+		urlID, granularity, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return points, nil
+}
+
+// CountUniqueVisitors merges the daily visitor sketches of the range, so a
+// visitor seen on several days counts once.
+func (c *Click) CountUniqueVisitors(ctx context.Context, urlID int, from, to time.Time) (int64, error) {
+	const op = "repository.postgres.Click.CountUniqueVisitors"
+
+	var sketches [][]byte
+	err := c.db.SelectContext(ctx, &sketches,
+		`
+			select sketch from click_visitors
+			where url_id = $1 and day >= $2 and day <= $3
+		`,
+		urlID, formatDay(from), formatDay(to.Add(-time.Nanosecond)),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	visitors, sketch := hll.New(), hll.New()
+	for _, data := range sketches {
+		if err := sketch.UnmarshalBinary(data); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		visitors.Merge(sketch)
+	}
+
+	return visitors.Count(), nil
+}
+
+func (c *Click) GetTopValues(
+	ctx context.Context,
+	urlID int,
+	dimension string,
+	from, to time.Time,
+	limit int,
+) ([]model.ClickCount, error) {
+	const op = "repository.postgres.Click.GetTopValues"
+
+	var counts []model.ClickCount
+	err := c.db.SelectContext(ctx, &counts,
+		`
+			select value, sum(clicks) as clicks from click_dimensions
+			where url_id = $1 and dimension = $2 and day >= $3 and day <= $4
+			group by value
+			order by clicks desc, value
+			limit $5
+		`,
+		urlID, dimension, formatDay(from), formatDay(to.Add(-time.Nanosecond)), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return counts, nil
+}
+
+// resolveClicks looks up the links clicked and keeps them from being
+// deleted until the batch is stored.
+func resolveClicks(ctx context.Context, tx *sqlx.Tx, clicks []model.Click) ([]clickRow, error) {
+	shortCodes := make([]string, 0, len(clicks))
+	for _, click := range clicks {
+		shortCodes = append(shortCodes, click.ShortCode)
+	}
+	slices.Sort(shortCodes)
+	shortCodes = slices.Compact(shortCodes)
+
+	var urls []struct {
+		ID        int    `db:"id"`
+		ShortCode string `db:"short_code"`
+	}
+	err := tx.SelectContext(ctx, &urls,
+		`
+			select id, short_code from urls
+			where short_code = any($1)
+			order by id
+			for key share
+		`,
+		pq.Array(shortCodes),
+	)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+
+	ids := make(map[string]int, len(urls))
+	for _, url := range urls {
+		ids[url.ShortCode] = url.ID
+	}
+
+	rows := make([]clickRow, 0, len(clicks))
+	for _, click := range clicks {
+		if id, ok := ids[click.ShortCode]; ok {
+			rows = append(rows, clickRow{Click: click, URLID: id})
+		}
+	}
+	return rows, nil
+}
+
+// mergeVisitors adds the batch sketches to the stored ones. Sketches are
+// merged here rather than in SQL, so the rows are created first and then
+// locked, in the same order by every batch.
+func mergeVisitors(ctx context.Context, tx *sqlx.Tx, visitors []visitorSketch) error {
+	urlIDs := make([]int64, len(visitors))
+	days := make([]string, len(visitors))
+	for i, visitor := range visitors {
+		urlIDs[i], days[i] = int64(visitor.URLID), visitor.Day
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`
+			insert into click_visitors (url_id, day, sketch)
+			select url_id, day, ''::bytea from unnest($1::integer[], $2::date[]) as v(url_id, day)
+			order by url_id, day
+			on conflict (url_id, day) do nothing
+		`,
+		pq.Array(urlIDs), pq.Array(days),
+	)
+	if err != nil {
+		return mapErr(err)
+	}
+
+	var stored []struct {
+		URLID  int    `db:"url_id"`
+		Day    string `db:"day"`
+		Sketch []byte `db:"sketch"`
+	}
+	err = tx.SelectContext(ctx, &stored,
+		`
+			select url_id, to_char(day, 'YYYY-MM-DD') as day, sketch from click_visitors
+			where (url_id, day) in (select * from unnest($1::integer[], $2::date[]))
+			order by url_id, day
+			for update
+		`,
+		pq.Array(urlIDs), pq.Array(days),
+	)
+	if err != nil {
+		return mapErr(err)
+	}
+
+	merged := make(map[visitorKey][]byte, len(stored))
+	for _, row := range stored {
+		merged[visitorKey{URLID: row.URLID, Day: row.Day}] = row.Sketch
+	}
+
+	sketches := make([][]byte, len(visitors))
+	sketch := hll.New()
+	for i, visitor := range visitors {
+		if err := sketch.UnmarshalBinary(merged[visitor.visitorKey]); err != nil {
+			return err
+		}
+		sketch.Merge(visitor.Sketch)
+		if sketches[i], err = sketch.MarshalBinary(); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`
+			update click_visitors set sketch = v.sketch
+			from unnest($1::integer[], $2::date[], $3::bytea[]) as v(url_id, day, sketch)
+			where click_visitors.url_id = v.url_id and click_visitors.day = v.day
+		`,
+		pq.Array(urlIDs), pq.Array(days), pq.Array(sketches),
+	)
+	if err != nil {
+		return mapErr(err)
+	}
+
+	return nil
+}
+
+// rollup aggregates a batch in memory so every rollup row is upserted once
+// per statement. Rows are sorted so that concurrent batches lock them in the
+// same order.
+func rollup(clicks []clickRow) ([]seriesRow, []dimensionRow, []visitorSketch) {
+	seriesIdx := make(map[seriesRow]int)
+	dimensionIdx := make(map[dimensionRow]int)
+	visitorIdx := make(map[visitorKey]int)
+
+	var (
+		series     []seriesRow
+		dimensions []dimensionRow
+		visitors   []visitorSketch
+	)
+
+	for _, click := range clicks {
+		at := click.ClickedAt.UTC()
+		day := formatDay(at)
+
+		buckets := [...]seriesRow{
+			{URLID: click.URLID, Granularity: model.GranularityMinute, BucketStart: at.Truncate(time.Minute)},
+			{URLID: click.URLID, Granularity: model.GranularityHour, BucketStart: at.Truncate(time.Hour)},
+			{URLID: click.URLID, Granularity: model.GranularityDay, BucketStart: at.Truncate(24 * time.Hour)},
+		}
+		for _, key := range buckets {
+			if i, ok := seriesIdx[key]; ok {
+				series[i].Clicks++
+				continue
+			}
+			seriesIdx[key] = len(series)
+			key.Clicks = 1
+			series = append(series, key)
+		}
+
+		values := [...]dimensionRow{
+			{URLID: click.URLID, Day: day, Dimension: model.DimensionReferrer, Value: click.Referrer},
+			{URLID: click.URLID, Day: day, Dimension: model.DimensionCountry, Value: click.Country},
+			{URLID: click.URLID, Day: day, Dimension: model.DimensionBrowser, Value: click.Browser},
+			{URLID: click.URLID, Day: day, Dimension: model.DimensionOS, Value: click.OS},
+		}
+		for _, key := range values {
+			if i, ok := dimensionIdx[key]; ok {
+				dimensions[i].Clicks++
+				continue
+			}
+			dimensionIdx[key] = len(dimensions)
+			key.Clicks = 1
+			dimensions = append(dimensions, key)
+		}
+
+		key := visitorKey{URLID: click.URLID, Day: day}
+		i, ok := visitorIdx[key]
+		if !ok {
+			i = len(visitors)
+			visitorIdx[key] = i
+			visitors = append(visitors, visitorSketch{visitorKey: key, Sketch: hll.New()})
+		}
+		visitors[i].Sketch.Add(click.IP + "|" + click.UserAgent)
+	}
+
+	slices.SortFunc(series, func(a, b seriesRow) int {
+		return cmp.Or(
+			cmp.Compare(a.URLID, b.URLID),
+			strings.Compare(a.Granularity, b.Granularity),
+			a.BucketStart.Compare(b.BucketStart),
+		)
+	})
+	slices.SortFunc(dimensions, func(a, b dimensionRow) int {
+		return cmp.Or(
+			cmp.Compare(a.URLID, b.URLID),
+			strings.Compare(a.Dimension, b.Dimension),
+			strings.Compare(a.Day, b.Day),
+			strings.Compare(a.Value, b.Value),
+		)
+	})
+	slices.SortFunc(visitors, func(a, b visitorSketch) int {
+		return cmp.Or(
+			cmp.Compare(a.URLID, b.URLID),
+			strings.Compare(a.Day, b.Day),
+		)
+	})
+
+	return series, dimensions, visitors
+}
+
+// formatDay renders the UTC calendar day of t, so date columns do not
+// depend on the session time zone. Day ranges in queries are inclusive, hence
+// callers step back from an exclusive upper bound.
+func formatDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
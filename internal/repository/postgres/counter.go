@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// counterLockKey serializes leases and reseeds so the values between
+// nextval and setval can't be handed out twice.
+const counterLockKey = 7_411_001
+
+// Counter is the short code counter kept in the short_code_counter
+// sequence, for deployments that don't want Valkey on the write path.
+type Counter struct {
+	db *sqlx.DB
+}
+
+func NewCounter(
+	db *sqlx.DB,
+) *Counter {
+	return &Counter{db: db}
+}
+
+func (c *Counter) Incr(ctx context.Context) (int, error) {
+	const op = "repository.postgres.Counter.Incr"
+
+	value, err := c.IncrBy(ctx, 1)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return value, nil
+}
+
+// IncrBy reserves the next n values and returns the last of them.
+func (c *Counter) IncrBy(ctx context.Context, n int) (int, error) {
+	const op = "repository.postgres.Counter.IncrBy"
+
+	var value int
+	err := c.withLock(ctx, func(tx *sqlx.Tx) error {
+		return tx.GetContext(ctx, &value,
+			`
+				select setval('short_code_counter', nextval('short_code_counter') + $1 - 1)
+			`,
+			n,
+		)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return value, nil
+}
+
+// Reseed makes sure the sequence never hands out floor or anything below it
+// again.
+func (c *Counter) Reseed(ctx context.Context, floor int) error {
+	const op = "repository.postgres.Counter.Reseed"
+
+	if floor < 1 {
+		return nil
+	}
+
+	err := c.withLock(ctx, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`
+				select setval('short_code_counter', $1)
+				from short_code_counter
+				where case when is_called then last_value else last_value - 1 end < $1
+			`,
+			floor,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return nil
+}
+
+func (c *Counter) withLock(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := c.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	if _, err := tx.ExecContext(ctx, "select pg_advisory_xact_lock($1)", counterLockKey); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
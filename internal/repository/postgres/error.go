@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"url_shortener/internal/model"
+
+	"github.com/lib/pq"
+)
+
+const (
+	uniqueViolation = "23505"
+	// connectionException is the class of errors about the connection
+	// itself, such as the server dropping it.
+	connectionException = "08"
+	tooManyConnections  = "53300"
+	adminShutdown       = "57P01"
+	crashShutdown       = "57P02"
+	cannotConnectNow    = "57P03"
+)
+
+// mapErr turns driver errors into model errors. Failures to reach the
+// database become model.ErrUnavailable, the error keeps its cause.
+func mapErr(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == uniqueViolation:
+			return model.ErrConflict
+		case pqErr.Code.Class() == connectionException,
+			pqErr.Code == tooManyConnections,
+			pqErr.Code == adminShutdown,
+			pqErr.Code == crashShutdown,
+			pqErr.Code == cannotConnectNow:
+			return fmt.Errorf("%w: %w", model.ErrUnavailable, err)
+		}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", model.ErrUnavailable, err)
+	}
+
+	return err
+}
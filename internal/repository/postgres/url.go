@@ -3,9 +3,12 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 	"url_shortener/internal/model"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type URL struct {
@@ -18,38 +21,379 @@ func NewURL(
 	return &URL{db: db}
 }
 
-func (u *URL) Create(ctx context.Context, shortCode, originalURL string) (*model.URL, error) {
+func (u *URL) Create(ctx context.Context, url *model.URL) (*model.URL, error) {
 	const op = "repository.postgres.URL.Create"
 
+	var created model.URL
+	err := u.db.GetContext(ctx, &created,
+		`
+			insert into urls (
+				short_code, original_url, canonical_url, original_url_hash, expires_at, owner_id, counter
+			)
+			values ($1, $2, $3, encode(sha256(convert_to($3, 'UTF8')), 'hex'), $4, $5, $6)
+			returning *
+		`,
+		url.ShortCode, url.OriginalURL, url.CanonicalURL, url.ExpiresAt, url.OwnerID, url.Counter,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return &created, nil
+}
+
+// CreateBatch inserts urls with a single statement. Rows whose short code
+// is taken are skipped rather than failing the others, so the result holds
+// only the inserted rows, in no particular order.
+func (u *URL) CreateBatch(ctx context.Context, urls []*model.URL) ([]model.URL, error) {
+	const op = "repository.postgres.URL.CreateBatch"
+
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	const columns = 6
+	rows := make([]string, 0, len(urls))
+	args := make([]any, 0, len(urls)*columns)
+	for i, url := range urls {
+		n := i * columns
+		rows = append(rows, fmt.Sprintf(
+			"($%d, $%d, $%d, encode(sha256(convert_to($%d, 'UTF8')), 'hex'), $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+3, n+4, n+5, n+6,
+		))
+		args = append(args, url.ShortCode, url.OriginalURL, url.CanonicalURL, url.ExpiresAt, url.OwnerID, url.Counter)
+	}
+
+	query := `
+		insert into urls (
+			short_code, original_url, canonical_url, original_url_hash, expires_at, owner_id, counter
+		)
+		values ` + strings.Join(rows, ", ") + `
+		on conflict (short_code) do nothing
+		returning *
+	`
+
+	created := make([]model.URL, 0, len(urls))
+	err := u.db.SelectContext(ctx, &created, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return created, nil
+}
+
+func (u *URL) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	const op = "repository.postgres.URL.GetByShortCode"
+
 	var url model.URL
 	err := u.db.GetContext(ctx, &url,
 		`
-			insert into urls (short_code, original_url)
-			values ($1, $2)
+			select * from urls where short_code = $1
+		`,
+		shortCode,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return &url, nil
+}
+
+// ListShortCodes returns up to limit links after afterID in id order, with
+// only the id and short code set, for scanning every code in pages.
+func (u *URL) ListShortCodes(ctx context.Context, afterID, limit int) ([]model.URL, error) {
+	const op = "repository.postgres.URL.ListShortCodes"
+
+	var urls []model.URL
+	err := u.db.SelectContext(ctx, &urls,
+		`
+			select id, short_code from urls
+			where id > $1
+			order by id
+			limit $2
+		`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return urls, nil
+}
+
+// GetLatestByCanonicalURL returns the newest unexpired link of the owner,
+// or of no owner when ownerID is nil, pointing at canonicalURL.
+// Destinations are matched by hash since urls can be too long for a btree
+// index.
+func (u *URL) GetLatestByCanonicalURL(ctx context.Context, ownerID *string, canonicalURL string) (*model.URL, error) {
+	const op = "repository.postgres.URL.GetLatestByCanonicalURL"
+
+	var url model.URL
+	err := u.db.GetContext(ctx, &url,
+		`
+			select * from urls
+			where original_url_hash = encode(sha256(convert_to($1, 'UTF8')), 'hex')
+				and canonical_url = $1
+				and owner_id is not distinct from $2
+				and (expires_at is null or expires_at > now())
+			order by id desc
+			limit 1
+		`,
+		canonicalURL, ownerID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return &url, nil
+}
+
+// MaxCounter returns the highest counter value links were generated from.
+func (u *URL) MaxCounter(ctx context.Context) (int, error) {
+	const op = "repository.postgres.URL.MaxCounter"
+
+	var value int
+	err := u.db.GetContext(ctx, &value, "select coalesce(max(counter), 0) from urls")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return value, nil
+}
+
+// ListUncounted returns, by id after afterID, the links without a counter
+// value created before the first link that has one. They predate recording
+// the value, so their codes may stand for counter values.
+func (u *URL) ListUncounted(ctx context.Context, afterID, limit int) ([]model.URL, error) {
+	const op = "repository.postgres.URL.ListUncounted"
+
+	urls := make([]model.URL, 0, limit)
+	err := u.db.SelectContext(ctx, &urls,
+		`
+			with first_counted as (
+				select min(id) as id from urls where counter is not null
+			)
+			select urls.* from urls, first_counted
+			where urls.counter is null and urls.id > $1
+				and (first_counted.id is null or urls.id < first_counted.id)
+			order by urls.id
+			limit $2
+		`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return urls, nil
+}
+
+func (u *URL) DeleteExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	const op = "repository.postgres.URL.DeleteExpired"
+
+	result, err := u.db.ExecContext(ctx,
+		`
+			delete from urls where id in (
+				select id from urls
+				where expires_at <= $1
+				order by expires_at
+				limit $2
+				for update skip locked
+			)
+		`,
+		before, limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return int(count), nil
+}
+
+func (u *URL) Update(ctx context.Context, shortCode, originalURL, canonicalURL string) (*model.URL, error) {
+	const op = "repository.postgres.URL.Update"
+
+	var url model.URL
+	err := u.db.GetContext(ctx, &url,
+		`
+			update urls set
+				original_url = $2,
+				canonical_url = $3,
+				original_url_hash = encode(sha256(convert_to($3, 'UTF8')), 'hex')
+			where short_code = $1
 			returning *
 		`,
-		shortCode, originalURL,
+		shortCode, originalURL, canonicalURL,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
 	}
 
 	return &url, nil
 }
 
-func (u *URL) GetOriginalURLByShortCode(ctx context.Context, shortCode string) (string, error) {
-	const op = "repository.postgres.URL.GetOriginalURLByShortCode"
+// ListUncanonical returns, by id after afterID, the links whose canonical
+// url is their original url, as migration 000010 left them.
+func (u *URL) ListUncanonical(ctx context.Context, afterID, limit int) ([]model.URL, error) {
+	const op = "repository.postgres.URL.ListUncanonical"
 
-	var originalURL string
-	err := u.db.GetContext(ctx, &originalURL,
+	urls := make([]model.URL, 0, limit)
+	err := u.db.SelectContext(ctx, &urls,
 		`
-			select original_url from urls where short_code = $1
+			select * from urls
+			where canonical_url = original_url and id > $1
+			order by id
+			limit $2
+		`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return urls, nil
+}
+
+func (u *URL) UpdateCanonicalURL(ctx context.Context, id int, canonicalURL string) error {
+	const op = "repository.postgres.URL.UpdateCanonicalURL"
+
+	_, err := u.db.ExecContext(ctx,
+		`
+			update urls set
+				canonical_url = $2,
+				original_url_hash = encode(sha256(convert_to($2, 'UTF8')), 'hex')
+			where id = $1
+		`,
+		id, canonicalURL,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return nil
+}
+
+func (u *URL) Delete(ctx context.Context, shortCode string) error {
+	const op = "repository.postgres.URL.Delete"
+
+	result, err := u.db.ExecContext(ctx,
+		`
+			delete from urls where short_code = $1
 		`,
 		shortCode,
 	)
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s: %w", op, model.ErrNotFound)
+	}
+
+	return nil
+}
+
+func (u *URL) List(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	const op = "repository.postgres.URL.List"
+
+	var (
+		conds []string
+		args  []any
+	)
+	addCond := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.OwnerID != nil {
+		addCond("owner_id = $%d", *filter.OwnerID)
+	}
+	if filter.AfterID > 0 {
+		addCond("id < $%d", filter.AfterID)
+	}
+	if filter.CreatedFrom != nil {
+		addCond("created_at >= $%d", filter.CreatedFrom.UTC())
+	}
+	if filter.CreatedTo != nil {
+		addCond("created_at < $%d", filter.CreatedTo.UTC())
+	}
+	if filter.Search != "" {
+		addCond(`original_url ilike '%%' || $%d || '%%'`, escapeLike(filter.Search))
+	}
+
+	query := "select * from urls"
+	if len(conds) > 0 {
+		query += " where " + strings.Join(conds, " and ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" order by id desc limit $%d", len(args))
+
+	urls := make([]model.URL, 0, filter.Limit)
+	err := u.db.SelectContext(ctx, &urls, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
 	}
 
-	return originalURL, nil
+	return urls, nil
+}
+
+// ListByShortCodes returns the links with the given short codes that
+// exist, in no particular order.
+func (u *URL) ListByShortCodes(ctx context.Context, shortCodes []string) ([]model.URL, error) {
+	const op = "repository.postgres.URL.ListByShortCodes"
+
+	urls := make([]model.URL, 0, len(shortCodes))
+	err := u.db.SelectContext(ctx, &urls,
+		`
+			select * from urls where short_code = any($1)
+		`,
+		pq.Array(shortCodes),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return urls, nil
+}
+
+// ListPopular returns up to limit unexpired links with the most clicks
+// since the given time, most clicked first, counted from the hourly click
+// rollups.
+func (u *URL) ListPopular(ctx context.Context, since time.Time, limit int) ([]model.URL, error) {
+	const op = "repository.postgres.URL.ListPopular"
+
+	urls := make([]model.URL, 0, limit)
+	err := u.db.SelectContext(ctx, &urls,
+		`
+			select urls.* from urls
+			join (
+				select url_id, sum(clicks) as clicks from click_series
+				where granularity = $1 and bucket_start >= $2
+				group by url_id
+				order by clicks desc
+				limit $3
+			) top on top.url_id = urls.id
+			where urls.expires_at is null or urls.expires_at > now()
+			order by top.clicks desc
+		`,
+		model.GranularityHour, since.UTC(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapErr(err))
+	}
+
+	return urls, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
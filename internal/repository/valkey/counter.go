@@ -3,10 +3,23 @@ package valkey
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	valkeygo "github.com/valkey-io/valkey-go"
 )
 
+// reseedScript raises the counter to ARGV[1] unless it is already higher
+// and returns the resulting value.
+var reseedScript = valkeygo.NewLuaScript(`
+local current = tonumber(redis.call('GET', KEYS[1])) or 0
+local floor = tonumber(ARGV[1])
+if current < floor then
+	redis.call('SET', KEYS[1], floor)
+	return floor
+end
+return current
+`)
+
 type Counter struct {
 	client valkeygo.Client
 }
@@ -37,6 +50,41 @@ func (c *Counter) Incr(ctx context.Context) (int, error) {
 	return int(count), nil
 }
 
+// IncrBy reserves the next n values and returns the last of them.
+func (c *Counter) IncrBy(ctx context.Context, n int) (int, error) {
+	const op = "repository.valkey.Counter.IncrBy"
+
+	key := c.buildKey()
+	cmd := c.client.B().Incrby().Key(key).Increment(int64(n)).Build()
+	result := c.client.Do(ctx, cmd)
+	if result.Error() != nil {
+		return 0, fmt.Errorf("%s: %w", op, result.Error())
+	}
+
+	count, err := result.AsInt64()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return int(count), nil
+}
+
+// Reseed makes sure the counter never hands out floor or anything below it
+// again, for example after the key was lost.
+func (c *Counter) Reseed(ctx context.Context, floor int) error {
+	const op = "repository.valkey.Counter.Reseed"
+
+	err := reseedScript.Exec(ctx, c.client,
+		[]string{c.buildKey()},
+		[]string{strconv.Itoa(floor)},
+	).Error()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (c *Counter) buildKey() string {
 	return "counters:short_code"
 }
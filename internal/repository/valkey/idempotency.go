@@ -0,0 +1,101 @@
+package valkey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"url_shortener/internal/model"
+
+	valkeygo "github.com/valkey-io/valkey-go"
+)
+
+// Idempotency keeps Idempotency-Key records. A key is reserved for lockTTL
+// while its request runs and kept for ttl once the response is saved.
+type Idempotency struct {
+	ttl     time.Duration
+	lockTTL time.Duration
+	client  valkeygo.Client
+}
+
+func NewIdempotency(
+	ttl time.Duration,
+	lockTTL time.Duration,
+	client valkeygo.Client,
+) *Idempotency {
+	return &Idempotency{
+		ttl:     ttl,
+		lockTTL: lockTTL,
+		client:  client,
+	}
+}
+
+// Reserve claims key for a request with the given fingerprint. It reports
+// true when the key was free; otherwise it returns the record of the
+// request that claimed it first.
+func (i *Idempotency) Reserve(
+	ctx context.Context,
+	key, fingerprint string,
+) (*model.IdempotencyRecord, bool, error) {
+	const op = "repository.valkey.Idempotency.Reserve"
+
+	record := &model.IdempotencyRecord{Fingerprint: fingerprint}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cmd := i.client.B().Set().Key(i.buildKey(key)).Value(string(data)).Nx().Px(i.lockTTL).Build()
+	err = i.client.Do(ctx, cmd).Error()
+	if err == nil {
+		return record, true, nil
+	}
+	if !valkeygo.IsValkeyNil(err) {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cmd = i.client.B().Get().Key(i.buildKey(key)).Build()
+	data, err = i.client.Do(ctx, cmd).AsBytes()
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var existing model.IdempotencyRecord
+	if err := json.Unmarshal(data, &existing); err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &existing, false, nil
+}
+
+func (i *Idempotency) Save(ctx context.Context, key string, record *model.IdempotencyRecord) error {
+	const op = "repository.valkey.Idempotency.Save"
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	cmd := i.client.B().Set().Key(i.buildKey(key)).Value(string(data)).Px(i.ttl).Build()
+	if err := i.client.Do(ctx, cmd).Error(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Release frees key so the request can be retried.
+func (i *Idempotency) Release(ctx context.Context, key string) error {
+	const op = "repository.valkey.Idempotency.Release"
+
+	cmd := i.client.B().Del().Key(i.buildKey(key)).Build()
+	if err := i.client.Do(ctx, cmd).Error(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (i *Idempotency) buildKey(key string) string {
+	return "idempotency:" + key
+}
@@ -0,0 +1,57 @@
+package valkey
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	valkeygo "github.com/valkey-io/valkey-go"
+)
+
+// invalidationChannel carries the cache keys ("urls:<code>") of links that
+// were updated or deleted, so every instance can drop its local copy.
+const invalidationChannel = "urls:invalidations"
+
+// Invalidation receives the invalidations URL publishes.
+type Invalidation struct {
+	client valkeygo.Client
+}
+
+func NewInvalidation(
+	client valkeygo.Client,
+) *Invalidation {
+	return &Invalidation{
+		client: client,
+	}
+}
+
+// Subscribe passes the short code of every invalidation to onInvalidate
+// until ctx is done or the subscription fails for good. onSubscribed runs
+// each time the subscription is confirmed, including when the client
+// resubscribes after a reconnect, since invalidations sent in between are
+// lost. Both callbacks run on the connection goroutine and must not block.
+func (i *Invalidation) Subscribe(
+	ctx context.Context,
+	onSubscribed func(),
+	onInvalidate func(shortCode string),
+) error {
+	const op = "repository.valkey.Invalidation.Subscribe"
+
+	ctx = valkeygo.WithOnSubscriptionHook(ctx, func(s valkeygo.PubSubSubscription) {
+		if s.Kind == "subscribe" {
+			onSubscribed()
+		}
+	})
+
+	cmd := i.client.B().Subscribe().Channel(invalidationChannel).Build()
+	err := i.client.Receive(ctx, cmd, func(msg valkeygo.PubSubMessage) {
+		if shortCode, ok := strings.CutPrefix(msg.Message, "urls:"); ok {
+			onInvalidate(shortCode)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package valkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+	"url_shortener/internal/model"
+	"url_shortener/internal/repository"
+	"url_shortener/internal/utils/breaker"
+
+	valkeygo "github.com/valkey-io/valkey-go"
+)
+
+// gcraScript implements the generic cell rate algorithm. The key holds the
+// theoretical arrival time (TAT) in milliseconds; a request is allowed while
+// TAT stays within period of now, which permits bursts of up to limit. A
+// request of cost n advances TAT by n intervals.
+//
+// ARGV: emission interval ms, period ms, cost.
+// Returns: allowed (0/1), remaining, retry after ms, reset ms.
+var gcraScript = valkeygo.NewLuaScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local interval = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+tat = math.max(tat, now)
+
+local new_tat = tat + interval * cost
+local allow_at = new_tat - period
+if allow_at > now then
+	return {0, math.floor((period - (tat - now)) / interval), allow_at - now, tat - now}
+end
+
+redis.call('SET', KEYS[1], new_tat, 'PX', new_tat - now)
+return {1, math.floor((period - (new_tat - now)) / interval), 0, new_tat - now}
+`)
+
+// RateLimiter shares limits between instances through Valkey. While Valkey
+// is unreachable it falls back to the per-process limiter, so requests are
+// still limited, only per instance. Once the breaker trips requests go
+// straight to the fallback instead of waiting on Valkey first.
+type RateLimiter struct {
+	name     string
+	limit    int
+	period   time.Duration
+	logger   *slog.Logger
+	client   valkeygo.Client
+	breaker  *breaker.Breaker
+	fallback repository.RateLimiter
+}
+
+func NewRateLimiter(
+	name string,
+	limit int,
+	period time.Duration,
+	logger *slog.Logger,
+	client valkeygo.Client,
+	breaker *breaker.Breaker,
+	fallback repository.RateLimiter,
+) *RateLimiter {
+	return &RateLimiter{
+		name:     name,
+		limit:    limit,
+		period:   period,
+		logger:   logger,
+		client:   client,
+		breaker:  breaker,
+		fallback: fallback,
+	}
+}
+
+// Allow takes cost tokens from the bucket of key. Like the local limiter
+// it denies a cost above limit without touching the bucket, which also
+// keeps allow from dividing by a limit that isn't positive.
+func (r *RateLimiter) Allow(ctx context.Context, key string, cost int) (*model.RateLimit, error) {
+	const op = "repository.valkey.RateLimiter.Allow"
+
+	cost = max(cost, 1)
+	if cost > r.limit {
+		return &model.RateLimit{Limit: r.limit}, nil
+	}
+	if _, ok := r.breaker.Allow(); ok {
+		result, err := r.allow(ctx, key, cost)
+		r.record(err)
+		if err == nil {
+			return result, nil
+		}
+
+		r.logger.WarnContext(ctx, "failed to check rate limit, using local limiter",
+			slog.String("limiter", r.name),
+			slog.Any("error", fmt.Errorf("%s: %w", op, err)),
+		)
+	}
+
+	result, err := r.fallback.Allow(ctx, key, cost)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// allow runs the script, limit must be positive.
+func (r *RateLimiter) allow(ctx context.Context, key string, cost int) (*model.RateLimit, error) {
+	interval := r.period.Milliseconds() / int64(r.limit)
+	values, err := gcraScript.Exec(ctx, r.client,
+		[]string{r.buildKey(key)},
+		[]string{
+			strconv.FormatInt(max(interval, 1), 10),
+			strconv.FormatInt(r.period.Milliseconds(), 10),
+			strconv.Itoa(cost),
+		},
+	).AsIntSlice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected script reply of %d values", len(values))
+	}
+
+	return &model.RateLimit{
+		Allowed:    values[0] == 1,
+		Limit:      r.limit,
+		Remaining:  int(max(values[1], 0)),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+func (r *RateLimiter) record(err error) {
+	if errors.Is(err, context.Canceled) {
+		r.breaker.Abandon()
+		return
+	}
+	r.breaker.Record(err != nil)
+}
+
+func (r *RateLimiter) buildKey(key string) string {
+	return fmt.Sprintf("ratelimit:%s:%s", r.name, key)
+}
@@ -0,0 +1,137 @@
+package valkey_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+	"url_shortener/internal/repository/memory"
+	"url_shortener/internal/repository/valkey"
+	"url_shortener/internal/utils/breaker"
+	utilsvalkey "url_shortener/internal/utils/valkey"
+)
+
+// newRateLimiter returns a limiter on the Valkey server at VALKEY_TEST_URL,
+// the test is skipped without one. Every limiter gets fresh keys.
+func newRateLimiter(t *testing.T, limit int, period time.Duration) *valkey.RateLimiter {
+	t.Helper()
+
+	url := os.Getenv("VALKEY_TEST_URL")
+	if url == "" {
+		t.Skip("VALKEY_TEST_URL is not set")
+	}
+	client, err := utilsvalkey.NewValkeyClient(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	return valkey.NewRateLimiter(
+		t.Name()+":"+strconv.FormatInt(time.Now().UnixNano(), 10),
+		limit,
+		period,
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		client,
+		breaker.New(0, 0),
+		memory.NewRateLimiter(limit, period, 10),
+	)
+}
+
+func TestRateLimiterGCRA(t *testing.T) {
+	limiter := newRateLimiter(t, 3, time.Minute)
+	ctx := context.Background()
+
+	for i := range 3 {
+		result, err := limiter.Allow(ctx, "client", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d: allowed %v, remaining %d", i, result.Allowed, result.Remaining)
+		}
+	}
+
+	result, err := limiter.Allow(ctx, "client", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed {
+		t.Fatal("request past the burst was allowed")
+	}
+	// One request is let through every 20s.
+	if result.RetryAfter <= 19*time.Second || result.RetryAfter > 20*time.Second {
+		t.Fatalf("RetryAfter = %s, want about 20s", result.RetryAfter)
+	}
+	if result.Reset <= 59*time.Second || result.Reset > time.Minute {
+		t.Fatalf("Reset = %s, want about 1m", result.Reset)
+	}
+}
+
+func TestRateLimiterGCRARefills(t *testing.T) {
+	limiter := newRateLimiter(t, 2, 200*time.Millisecond)
+	ctx := context.Background()
+
+	for range 2 {
+		if result, _ := limiter.Allow(ctx, "client", 1); !result.Allowed {
+			t.Fatal("burst request was limited")
+		}
+	}
+	if result, _ := limiter.Allow(ctx, "client", 1); result.Allowed {
+		t.Fatal("request past the burst was allowed")
+	}
+
+	time.Sleep(120 * time.Millisecond)
+
+	if result, _ := limiter.Allow(ctx, "client", 1); !result.Allowed {
+		t.Fatal("request after an emission interval was limited")
+	}
+}
+
+func TestRateLimiterGCRACost(t *testing.T) {
+	limiter := newRateLimiter(t, 10, time.Minute)
+	ctx := context.Background()
+
+	result, _ := limiter.Allow(ctx, "client", 4)
+	if !result.Allowed || result.Remaining != 6 {
+		t.Fatalf("cost 4: allowed %v, remaining %d", result.Allowed, result.Remaining)
+	}
+	if result, _ = limiter.Allow(ctx, "client", 7); result.Allowed {
+		t.Fatal("cost 7 was allowed with 6 left")
+	}
+	if result, _ = limiter.Allow(ctx, "client", 6); !result.Allowed {
+		t.Fatal("cost 6 was limited with 6 left")
+	}
+	if result, _ = limiter.Allow(ctx, "fresh", 50); result.Allowed {
+		t.Fatal("cost over limit was allowed")
+	}
+	if result, _ = limiter.Allow(ctx, "fresh", 10); !result.Allowed {
+		t.Fatal("cost over limit took tokens")
+	}
+}
+
+// A limit that isn't positive denies every request before Valkey is
+// reached, so it needs no server.
+func TestRateLimiterNonPositiveLimit(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		limiter := valkey.NewRateLimiter(
+			"test",
+			limit,
+			time.Minute,
+			slog.New(slog.NewTextHandler(io.Discard, nil)),
+			nil,
+			breaker.New(0, 0),
+			memory.NewRateLimiter(limit, time.Minute, 10),
+		)
+
+		result, err := limiter.Allow(context.Background(), "client", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed {
+			t.Fatalf("limit %d allowed a request", limit)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package valkey
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -9,38 +11,75 @@ import (
 	"url_shortener/internal/repository"
 
 	valkeygo "github.com/valkey-io/valkey-go"
+	"golang.org/x/sync/singleflight"
 )
 
+const (
+	// notFoundValue is cached under the key of a code that doesn't exist.
+	// It can't be mistaken for a link, which is cached as a JSON object.
+	notFoundValue = "!"
+	// loadTimeout bounds a coalesced load, which doesn't stop when the
+	// request that started it is canceled since others wait for it too.
+	loadTimeout = 10 * time.Second
+)
+
+// URL caches links in front of another repository.URL. Concurrent misses
+// of the same code share one load, and codes that don't exist are cached
+// for notFoundTTL, zero disables that, so neither a hot link falling out
+// of the cache nor a scan of random codes floods the repository behind.
+//
+// With a softTTL a link cached longer than that is still served, while a
+// background load refreshes it, so popular links never wait on the
+// repository once cached. Zero disables it.
 type URL struct {
 	ttl           time.Duration
+	softTTL       time.Duration
+	notFoundTTL   time.Duration
 	logger        *slog.Logger
 	client        valkeygo.Client
 	urlRepository repository.URL
+
+	loads singleflight.Group
+}
+
+// cachedURL is a link as cached, RefreshAt is when it goes stale with a
+// soft TTL set. Entries cached without one count as stale.
+type cachedURL struct {
+	model.URL
+	RefreshAt *time.Time `json:"refresh_at,omitempty"`
 }
 
 func NewURL(
 	ttl time.Duration,
+	softTTL time.Duration,
+	notFoundTTL time.Duration,
 	logger *slog.Logger,
 	client valkeygo.Client,
 	urlRepository repository.URL,
 ) *URL {
 	return &URL{
 		ttl:           ttl,
+		softTTL:       softTTL,
+		notFoundTTL:   notFoundTTL,
 		logger:        logger,
 		client:        client,
 		urlRepository: urlRepository,
 	}
 }
 
-func (u *URL) Create(ctx context.Context, shortCode, originalURL string) (*model.URL, error) {
+func (u *URL) Create(ctx context.Context, url *model.URL) (*model.URL, error) {
 	const op = "repository.valkey.URL.Create"
 
-	url, err := u.urlRepository.Create(ctx, shortCode, originalURL)
+	url, err := u.urlRepository.Create(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := u.setCache(ctx, shortCode, originalURL); err != nil {
+	// The link exists now, caching and announcing it must not be cut short
+	// by the client going away.
+	ctx = context.WithoutCancel(ctx)
+
+	if err := u.setCache(ctx, url); err != nil {
 		u.logger.WarnContext(ctx, "failed to set cache",
 			slog.Int("id", url.ID),
 			slog.String("short_code", url.ShortCode),
@@ -49,62 +88,387 @@ func (u *URL) Create(ctx context.Context, shortCode, originalURL string) (*model
 			slog.Time("updated_at", url.UpdatedAt),
 			slog.Any("error", fmt.Errorf("%s: %w", op, err)),
 		)
+		u.clearCache(ctx, op, url.ShortCode)
 	}
 
+	u.publish(ctx, op, url.ShortCode)
+
 	return url, nil
 }
 
-func (u *URL) GetOriginalURLByShortCode(ctx context.Context, shortCode string) (string, error) {
-	const op = "repository.valkey.URL.GetOriginalURLByShortCode"
+// CreateBatch caches and announces the created links with one pipelined
+// round trip.
+func (u *URL) CreateBatch(ctx context.Context, urls []*model.URL) ([]model.URL, error) {
+	const op = "repository.valkey.URL.CreateBatch"
+
+	created, err := u.urlRepository.CreateBatch(ctx, urls)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(created) == 0 {
+		return created, nil
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	var uncached []string
+	cmds := make(valkeygo.Commands, 0, 2*len(created))
+	cached := make([]string, 0, len(created))
+	for i := range created {
+		cmd, err := u.setCacheCmd(&created[i])
+		if err != nil {
+			u.logger.WarnContext(ctx, "failed to set cache",
+				slog.String("short_code", created[i].ShortCode),
+				slog.Any("error", fmt.Errorf("%s: %w", op, err)),
+			)
+			uncached = append(uncached, created[i].ShortCode)
+			continue
+		}
+		cmds = append(cmds, cmd)
+		cached = append(cached, created[i].ShortCode)
+	}
+	for i := range created {
+		cmds = append(cmds, u.publishCmd(created[i].ShortCode))
+	}
+
+	for i, result := range u.client.DoMulti(ctx, cmds...) {
+		err := result.Error()
+		if err == nil {
+			continue
+		}
+		if i < len(cached) {
+			u.logger.WarnContext(ctx, "failed to set cache",
+				slog.String("short_code", cached[i]),
+				slog.Any("error", fmt.Errorf("%s: %w", op, err)),
+			)
+			uncached = append(uncached, cached[i])
+			continue
+		}
+		u.logger.WarnContext(ctx, "failed to publish cache invalidation",
+			slog.String("short_code", created[i-len(cached)].ShortCode),
+			slog.Any("error", fmt.Errorf("%s: %w", op, err)),
+		)
+	}
+
+	for _, shortCode := range uncached {
+		u.clearCache(ctx, op, shortCode)
+	}
+
+	return created, nil
+}
+
+func (u *URL) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	const op = "repository.valkey.URL.GetByShortCode"
 
-	originalURL, err := u.getCache(ctx, shortCode)
+	url, stale, err := u.getCache(ctx, shortCode)
 	if err == nil {
-		return originalURL, nil
+		if stale {
+			u.revalidate(ctx, shortCode)
+		}
+		return url, nil
+	}
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if !valkeygo.IsValkeyNil(err) {
 		u.logger.WarnContext(ctx, "failed to get cache",
 			slog.String("short_code", shortCode),
 			slog.Any("error", fmt.Errorf("%s: %w", op, err)),
-			slog.String("original_url", originalURL),
 		)
 	}
 
-	originalURL, err = u.urlRepository.GetOriginalURLByShortCode(ctx, shortCode)
+	loaded, err, _ := u.loads.Do(shortCode, func() (any, error) {
+		return u.load(context.WithoutCancel(ctx), shortCode)
+	})
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := u.setCache(ctx, shortCode, originalURL); err != nil {
+	url = new(model.URL)
+	*url = *loaded.(*model.URL) //nolint:errcheck,forcetypeassert // load only returns links
+	return url, nil
+}
+
+// load reads a link missing from the cache from the repository behind and
+// caches it, or the fact that it doesn't exist.
+func (u *URL) load(ctx context.Context, shortCode string) (*model.URL, error) {
+	const op = "repository.valkey.URL.load"
+
+	ctx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+
+	url, err := u.urlRepository.GetByShortCode(ctx, shortCode)
+	if errors.Is(err, model.ErrNotFound) {
+		if err := u.setNotFound(ctx, shortCode); err != nil {
+			u.logger.WarnContext(ctx, "failed to cache missing url",
+				slog.String("short_code", shortCode),
+				slog.Any("error", fmt.Errorf("%s: %w", op, err)),
+			)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.setCache(ctx, url); err != nil {
 		u.logger.WarnContext(ctx, "failed to set cache",
 			slog.String("short_code", shortCode),
+			slog.String("original_url", url.OriginalURL),
 			slog.Any("error", fmt.Errorf("%s: %w", op, err)),
-			slog.String("original_url", originalURL),
 		)
 	}
 
-	return originalURL, nil
+	return url, nil
 }
 
-func (u *URL) setCache(ctx context.Context, shortCode, originalURL string) error {
-	key := u.buildKey(shortCode)
-	cmd := u.client.B().Set().Key(key).Value(originalURL).Ex(u.ttl).Build()
+// revalidate refreshes a stale link in the background, sharing the load
+// with concurrent misses of the same code. A link deleted since is dropped
+// from the cache.
+func (u *URL) revalidate(ctx context.Context, shortCode string) {
+	const op = "repository.valkey.URL.revalidate"
+
+	ctx = context.WithoutCancel(ctx)
+	u.loads.DoChan(shortCode, func() (any, error) {
+		url, err := u.load(ctx, shortCode)
+		switch {
+		case errors.Is(err, model.ErrNotFound):
+			u.evict(ctx, op, shortCode)
+		case errors.Is(err, model.ErrUnavailable):
+			// The repository is known to be down, the stale link is
+			// served on until it is back.
+		case err != nil:
+			u.logger.WarnContext(ctx, "failed to revalidate cache",
+				slog.String("short_code", shortCode),
+				slog.Any("error", fmt.Errorf("%s: %w", op, err)),
+			)
+		}
+		return url, err
+	})
+}
+
+// Warm caches urls ahead of the first request for them with one pipelined
+// round trip, returning how many were cached. It never replaces an entry,
+// whatever was cached since the links were read is at least as fresh. The
+// links should be read right before the call: Update and Delete only evict
+// the entry, so a link changed in between would be cached as it was.
+func (u *URL) Warm(ctx context.Context, urls []model.URL) (int, error) {
+	const op = "repository.valkey.URL.Warm"
+
+	cmds := make(valkeygo.Commands, 0, len(urls))
+	for i := range urls {
+		value, ttl, err := u.encode(&urls[i])
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		if ttl <= 0 {
+			continue
+		}
+		key := u.buildKey(urls[i].ShortCode)
+		cmds = append(cmds, u.client.B().Set().Key(key).Value(value).Nx().Px(ttl).Build())
+	}
+	if len(cmds) == 0 {
+		return 0, nil
+	}
+
+	warmed := 0
+	for _, result := range u.client.DoMulti(ctx, cmds...) {
+		err := result.Error()
+		if valkeygo.IsValkeyNil(err) {
+			continue
+		}
+		if err != nil {
+			return warmed, fmt.Errorf("%s: %w", op, err)
+		}
+		warmed++
+	}
+
+	return warmed, nil
+}
+
+func (u *URL) GetLatestByCanonicalURL(ctx context.Context, ownerID *string, canonicalURL string) (*model.URL, error) {
+	const op = "repository.valkey.URL.GetLatestByCanonicalURL"
+
+	url, err := u.urlRepository.GetLatestByCanonicalURL(ctx, ownerID, canonicalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+// Update and Delete drop the cached entry both before and after the write:
+// the first delete aborts the change if the cache is unreachable, the second
+// one evicts a value re-cached by a concurrent read in between. After the
+// write the change is also announced to the in-process caches of every
+// instance.
+func (u *URL) Update(ctx context.Context, shortCode, originalURL, canonicalURL string) (*model.URL, error) {
+	const op = "repository.valkey.URL.Update"
+
+	if err := u.deleteCache(ctx, shortCode); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, err := u.urlRepository.Update(ctx, shortCode, originalURL, canonicalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	u.evict(ctx, op, shortCode)
+
+	return url, nil
+}
+
+func (u *URL) Delete(ctx context.Context, shortCode string) error {
+	const op = "repository.valkey.URL.Delete"
+
+	if err := u.deleteCache(ctx, shortCode); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := u.urlRepository.Delete(ctx, shortCode); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	u.evict(ctx, op, shortCode)
+
+	return nil
+}
+
+func (u *URL) List(ctx context.Context, filter model.URLFilter) ([]model.URL, error) {
+	const op = "repository.valkey.URL.List"
+
+	urls, err := u.urlRepository.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, nil
+}
+
+// evict runs after a write: failures are only logged since the write has
+// already happened, the local caches expire the entry on their own.
+func (u *URL) evict(ctx context.Context, op, shortCode string) {
+	u.clearCache(ctx, op, shortCode)
+	u.publish(ctx, op, shortCode)
+}
+
+// clearCache deletes whatever is cached for a link that couldn't be cached,
+// so a missing marker set before the link existed doesn't outlive it.
+func (u *URL) clearCache(ctx context.Context, op, shortCode string) {
+	if err := u.deleteCache(ctx, shortCode); err != nil {
+		u.logger.WarnContext(ctx, "failed to delete cache",
+			slog.String("short_code", shortCode),
+			slog.Any("error", fmt.Errorf("%s: %w", op, err)),
+		)
+	}
+}
+
+// publish announces a created or changed link to the in-process caches and
+// filters of every instance.
+func (u *URL) publish(ctx context.Context, op, shortCode string) {
+	if err := u.client.Do(ctx, u.publishCmd(shortCode)).Error(); err != nil {
+		u.logger.WarnContext(ctx, "failed to publish cache invalidation",
+			slog.String("short_code", shortCode),
+			slog.Any("error", fmt.Errorf("%s: %w", op, err)),
+		)
+	}
+}
+
+func (u *URL) publishCmd(shortCode string) valkeygo.Completed {
+	return u.client.B().Publish().Channel(invalidationChannel).Message(u.buildKey(shortCode)).Build()
+}
+
+func (u *URL) setCache(ctx context.Context, url *model.URL) error {
+	cmd, err := u.setCacheCmd(url)
+	if err != nil {
+		return err
+	}
 	result := u.client.Do(ctx, cmd)
 	return result.Error()
 }
 
-func (u *URL) getCache(ctx context.Context, shortCode string) (string, error) {
+// setCacheCmd builds the command caching url. A link expiring too soon to
+// be worth caching is deleted instead, dropping a missing marker with it.
+func (u *URL) setCacheCmd(url *model.URL) (valkeygo.Completed, error) {
+	key := u.buildKey(url.ShortCode)
+
+	value, ttl, err := u.encode(url)
+	if err != nil {
+		return valkeygo.Completed{}, err
+	}
+	if ttl <= 0 {
+		return u.client.B().Del().Key(key).Build(), nil
+	}
+
+	return u.client.B().Set().Key(key).Value(value).Px(ttl).Build(), nil
+}
+
+// encode returns the cached form of url and how long to keep it, which is
+// not positive for a link expiring too soon to be worth caching.
+func (u *URL) encode(url *model.URL) (string, time.Duration, error) {
+	ttl := u.ttl
+	if url.ExpiresAt != nil {
+		ttl = min(ttl, time.Until(*url.ExpiresAt))
+	}
+	if ttl <= 0 {
+		return "", ttl, nil
+	}
+
+	entry := cachedURL{URL: *url}
+	if u.softTTL > 0 {
+		refreshAt := time.Now().Add(u.softTTL)
+		entry.RefreshAt = &refreshAt
+	}
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return string(value), ttl, nil
+}
+
+// getCache returns model.ErrNotFound for codes cached as missing, stale
+// reports a link past its soft TTL.
+func (u *URL) getCache(ctx context.Context, shortCode string) (url *model.URL, stale bool, err error) {
 	key := u.buildKey(shortCode)
 	cmd := u.client.B().Get().Key(key).Build()
-	result := u.client.Do(ctx, cmd)
-	if result.Error() != nil {
-		return "", result.Error()
-	}
-	value, err := result.ToString()
+	value, err := u.client.Do(ctx, cmd).ToString()
 	if err != nil {
-		return "", err
+		return nil, false, err
+	}
+	if value == notFoundValue {
+		return nil, false, model.ErrNotFound
+	}
+	var entry cachedURL
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, false, err
+	}
+	stale = u.softTTL > 0 && (entry.RefreshAt == nil || !time.Now().Before(*entry.RefreshAt))
+	return &entry.URL, stale, nil
+}
+
+// setNotFound caches that shortCode doesn't exist. It never overwrites a
+// link cached in between, and creating the link overwrites it.
+func (u *URL) setNotFound(ctx context.Context, shortCode string) error {
+	if u.notFoundTTL <= 0 {
+		return nil
+	}
+	key := u.buildKey(shortCode)
+	cmd := u.client.B().Set().Key(key).Value(notFoundValue).Nx().Px(u.notFoundTTL).Build()
+	err := u.client.Do(ctx, cmd).Error()
+	if valkeygo.IsValkeyNil(err) {
+		return nil
 	}
-	return value, nil
+	return err
+}
+
+func (u *URL) deleteCache(ctx context.Context, shortCode string) error {
+	key := u.buildKey(shortCode)
+	cmd := u.client.B().Del().Key(key).Build()
+	result := u.client.Do(ctx, cmd)
+	return result.Error()
 }
 
 func (u *URL) buildKey(shortCode string) string {
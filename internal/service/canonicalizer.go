@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+)
+
+// canonicalizePageSize is how many links Canonicalizer normalizes per query.
+const canonicalizePageSize = 1000
+
+// Canonicalizer fills in the canonical url of links stored before
+// destinations were normalized, which migration 000010 set to the original
+// url as is, so reuse_existing finds them too.
+type Canonicalizer struct {
+	urlNormalizer          URLNormalizer
+	canonicalURLRepository CanonicalURLRepository
+}
+
+func NewCanonicalizer(
+	urlNormalizer URLNormalizer,
+	canonicalURLRepository CanonicalURLRepository,
+) *Canonicalizer {
+	return &Canonicalizer{
+		urlNormalizer:          urlNormalizer,
+		canonicalURLRepository: canonicalURLRepository,
+	}
+}
+
+// Run normalizes every link whose canonical url is still its original url
+// and returns how many changed and how many were skipped because the
+// normalizer rejects their url.
+func (c *Canonicalizer) Run(ctx context.Context) (int, int, error) {
+	const op = "service.Canonicalizer.Run"
+
+	var updated, skipped int
+	afterID := 0
+	for {
+		urls, err := c.canonicalURLRepository.ListUncanonical(ctx, afterID, canonicalizePageSize)
+		if err != nil {
+			return updated, skipped, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, url := range urls {
+			canonicalURL, err := c.urlNormalizer.Normalize(url.OriginalURL)
+			if err != nil {
+				skipped++
+				continue
+			}
+			if canonicalURL == url.CanonicalURL {
+				continue
+			}
+			if err := c.canonicalURLRepository.UpdateCanonicalURL(ctx, url.ID, canonicalURL); err != nil {
+				return updated, skipped, fmt.Errorf("%s: %w", op, err)
+			}
+			updated++
+		}
+
+		if len(urls) < canonicalizePageSize {
+			return updated, skipped, nil
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+}
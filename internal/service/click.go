@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"url_shortener/internal/model"
+	"url_shortener/internal/utils/useragent"
+)
+
+const (
+	topValuesLimit = 10
+
+	directReferrer = "direct"
+	unknownCountry = "unknown"
+)
+
+// bucketSteps maps a stats bucket to its width, the range used when the
+// caller omits one and the widest range allowed for it.
+var bucketSteps = map[string]struct {
+	width, defaultRange, maxRange time.Duration
+}{
+	model.GranularityMinute: {time.Minute, time.Hour, 24 * time.Hour},
+	model.GranularityHour:   {time.Hour, 24 * time.Hour, 31 * 24 * time.Hour},
+	model.GranularityDay:    {24 * time.Hour, 30 * 24 * time.Hour, 366 * 24 * time.Hour},
+}
+
+type Click struct {
+	clickQueue      ClickQueue
+	clickRepository ClickRepository
+	urlRepository   URLRepository
+}
+
+func NewClick(
+	clickQueue ClickQueue,
+	clickRepository ClickRepository,
+	urlRepository URLRepository,
+) *Click {
+	return &Click{
+		clickQueue:      clickQueue,
+		clickRepository: clickRepository,
+		urlRepository:   urlRepository,
+	}
+}
+
+// Record hands the click over to the queue without waiting for it to be
+// stored; events rejected by a full queue are counted there.
+func (c *Click) Record(ctx context.Context, click model.Click) {
+	click.Referrer = referrerHost(click.Referrer)
+	click.Country = countryFromLanguage(click.AcceptLanguage)
+	click.Browser, click.OS = useragent.Parse(click.UserAgent)
+
+	c.clickQueue.Enqueue(ctx, click)
+}
+
+func (c *Click) Stats(
+	ctx context.Context,
+	shortCode string,
+	from, to *time.Time,
+	bucket string,
+) (*model.ClickStats, error) {
+	const op = "service.Click.Stats"
+
+	link, err := c.urlRepository.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := authorize(ctx, link); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	step, ok := bucketSteps[bucket]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput, "unknown bucket"))
+	}
+
+	end := time.Now().UTC()
+	if to != nil {
+		end = to.UTC()
+	}
+	start := end.Add(-step.defaultRange)
+	if from != nil {
+		start = from.UTC()
+	}
+	start = start.Truncate(step.width)
+	if !start.Before(end) {
+		return nil, fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput, "from must be before to"))
+	}
+	if end.Sub(start) > step.maxRange {
+		return nil, fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput,
+			fmt.Sprintf("range is too wide for %s buckets, max %s", bucket, step.maxRange)))
+	}
+
+	points, err := c.clickRepository.GetSeries(ctx, link.ID, bucket, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Visitors and dimensions are only rolled up per day.
+	day := 24 * time.Hour
+	breakdownStart := start.Truncate(day)
+	breakdownEnd := end.Add(-time.Nanosecond).Truncate(day).Add(day)
+
+	stats := model.ClickStats{
+		ShortCode:     shortCode,
+		From:          start,
+		To:            end,
+		BreakdownFrom: breakdownStart,
+		BreakdownTo:   breakdownEnd,
+		Bucket:        bucket,
+		Series:        fillSeries(points, start, end, step.width),
+	}
+	for _, point := range points {
+		stats.TotalClicks += point.Clicks
+	}
+
+	stats.UniqueVisitors, err = c.clickRepository.CountUniqueVisitors(ctx, link.ID, breakdownStart, breakdownEnd)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for dimension, target := range map[string]*[]model.ClickCount{
+		model.DimensionReferrer: &stats.TopReferrers,
+		model.DimensionCountry:  &stats.TopCountries,
+		model.DimensionBrowser:  &stats.TopBrowsers,
+		model.DimensionOS:       &stats.TopOSes,
+	} {
+		*target, err = c.clickRepository.GetTopValues(ctx, link.ID, dimension, breakdownStart, breakdownEnd, topValuesLimit)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return &stats, nil
+}
+
+// fillSeries adds the empty buckets the rollup table has no rows for.
+func fillSeries(points []model.ClickPoint, start, end time.Time, width time.Duration) []model.ClickPoint {
+	byTime := make(map[time.Time]int64, len(points))
+	for _, point := range points {
+		byTime[point.Time.UTC()] = point.Clicks
+	}
+
+	series := make([]model.ClickPoint, 0, int(end.Sub(start)/width)+1)
+	for t := start; t.Before(end); t = t.Add(width) {
+		series = append(series, model.ClickPoint{Time: t, Clicks: byTime[t]})
+	}
+	return series
+}
+
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return directReferrer
+	}
+	parsed, err := url.Parse(referrer)
+	if err != nil || parsed.Hostname() == "" {
+		return directReferrer
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// countryFromLanguage approximates the visitor country with the region
+// subtag of the preferred language, e.g. "en-US,en;q=0.9" gives "US".
+func countryFromLanguage(acceptLanguage string) string {
+	tag, _, _ := strings.Cut(acceptLanguage, ",")
+	tag, _, _ = strings.Cut(tag, ";")
+	for i, subtag := range strings.Split(strings.TrimSpace(tag), "-") {
+		if i > 0 && len(subtag) == 2 {
+			return strings.ToUpper(subtag)
+		}
+	}
+	return unknownCountry
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"url_shortener/internal/model"
+)
+
+// Short code generation strategies, selectable with SHORT_CODE_STRATEGY and
+// per request with the strategy field.
+const (
+	StrategyCounter = "counter"
+	StrategyRandom  = "random"
+	StrategyHash    = "hash"
+	StrategyWords   = "words"
+)
+
+const base62Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// reconcilePageSize is how many links without a counter value Reconcile
+// decodes per query.
+const reconcilePageSize = 1000
+
+// aliasHorizon is how far past the highest recorded counter value an alias
+// may not decode to. Codes beyond it are only reached much later, and a
+// taken one then costs the counter a retry.
+const aliasHorizon = 1_000_000
+
+// ShortCodeGenerator sets the short code of a new link. Create retries with
+// the next attempt number when the code is already taken, so deterministic
+// generators must vary their output with attempt.
+type ShortCodeGenerator interface {
+	Generate(ctx context.Context, url *model.URL, attempt int) error
+}
+
+// conflictReconciler is implemented by generators that can tell a taken
+// code apart from bad luck and repair their state before Create retries.
+type conflictReconciler interface {
+	Reconcile(ctx context.Context) error
+}
+
+// codeDecoder is implemented by generators that can tell which of their
+// values an existing code stands for, so an imported code can be accounted
+// for before the generator produces it again.
+type codeDecoder interface {
+	Decode(shortCode string) (int, bool)
+}
+
+// aliasChecker is implemented by generators that would run into an alias
+// taking one of their next codes.
+type aliasChecker interface {
+	CheckAlias(ctx context.Context, alias string) error
+}
+
+// batchGenerator is implemented by generators that produce the codes of
+// many links more cheaply at once than one by one.
+type batchGenerator interface {
+	GenerateBatch(ctx context.Context, urls []*model.URL) error
+}
+
+// generateBatch sets the first attempt code of urls[i] for every index and
+// returns the errors of the indexes that got none.
+func generateBatch(ctx context.Context, generator ShortCodeGenerator, urls []*model.URL, indexes []int) map[int]error {
+	errs := make(map[int]error)
+
+	if batcher, ok := generator.(batchGenerator); ok {
+		batch := make([]*model.URL, len(indexes))
+		for j, i := range indexes {
+			batch[j] = urls[i]
+		}
+		if err := batcher.GenerateBatch(ctx, batch); err != nil {
+			for _, i := range indexes {
+				errs[i] = err
+			}
+		}
+		return errs
+	}
+
+	for _, i := range indexes {
+		if err := generator.Generate(ctx, urls[i], 0); err != nil {
+			errs[i] = err
+		}
+	}
+	return errs
+}
+
+// CounterGenerator encodes the next value of the shared counter and records
+// it with the link, so the counter can be restored from Postgres when it is
+// lost.
+type CounterGenerator struct {
+	shortCodeCodec         ShortCodeCodec
+	counterRepository      CounterRepository
+	counterFloorRepository CounterFloorRepository
+}
+
+func NewCounterGenerator(
+	shortCodeCodec ShortCodeCodec,
+	counterRepository CounterRepository,
+	counterFloorRepository CounterFloorRepository,
+) *CounterGenerator {
+	return &CounterGenerator{
+		shortCodeCodec:         shortCodeCodec,
+		counterRepository:      counterRepository,
+		counterFloorRepository: counterFloorRepository,
+	}
+}
+
+func (g *CounterGenerator) Generate(ctx context.Context, url *model.URL, _ int) error {
+	const op = "service.CounterGenerator.Generate"
+
+	num, err := g.counterRepository.Incr(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	shortCode, err := g.shortCodeCodec.Encode(num)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	url.ShortCode = shortCode
+	url.Counter = &num
+	return nil
+}
+
+// GenerateBatch takes the codes of all urls from one range of counter
+// values.
+func (g *CounterGenerator) GenerateBatch(ctx context.Context, urls []*model.URL) error {
+	const op = "service.CounterGenerator.GenerateBatch"
+
+	last, err := g.counterRepository.IncrBy(ctx, len(urls))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	first := last - len(urls) + 1
+	for i, url := range urls {
+		num := first + i
+		shortCode, err := g.shortCodeCodec.Encode(num)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		url.ShortCode = shortCode
+		url.Counter = &num
+	}
+
+	return nil
+}
+
+// Decode returns the counter value shortCode stands for and false when the
+// codec could not have produced it.
+func (g *CounterGenerator) Decode(shortCode string) (int, bool) {
+	return g.shortCodeCodec.Decode(shortCode)
+}
+
+// CheckAlias rejects an alias that decodes to one of the next aliasHorizon
+// counter values, so an alias can't make the counter hit a taken code
+// soon. Most aliases decode to values far beyond it, or not at all when
+// they contain '-' or '_'.
+func (g *CounterGenerator) CheckAlias(ctx context.Context, alias string) error {
+	const op = "service.CounterGenerator.CheckAlias"
+
+	num, ok := g.shortCodeCodec.Decode(alias)
+	if !ok {
+		return nil
+	}
+
+	floor, err := g.counterFloorRepository.MaxCounter(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if num > floor && num <= floor+aliasHorizon {
+		return fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput,
+			"alias is a short code the counter will generate soon, add '-' or '_' or make it longer"))
+	}
+
+	return nil
+}
+
+// Reconcile moves the counter past every value stored in Postgres. It runs
+// at startup and whenever a generated code turns out to be taken, which
+// happens when the counter was reset, e.g. Valkey lost its data. Codes of
+// links created before the value was recorded are decoded instead; one
+// minted by another strategy may decode too and push the counter further
+// than needed, which only skips values.
+func (g *CounterGenerator) Reconcile(ctx context.Context) error {
+	const op = "service.CounterGenerator.Reconcile"
+
+	floor, err := g.counterFloorRepository.MaxCounter(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	afterID := 0
+	for {
+		urls, err := g.counterFloorRepository.ListUncounted(ctx, afterID, reconcilePageSize)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, url := range urls {
+			if num, ok := g.shortCodeCodec.Decode(url.ShortCode); ok {
+				floor = max(floor, num)
+			}
+		}
+		if len(urls) < reconcilePageSize {
+			break
+		}
+		afterID = urls[len(urls)-1].ID
+	}
+
+	if err := g.counterRepository.Reseed(ctx, floor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RandomGenerator draws base62 codes of a fixed length; collisions are
+// caught by the unique index on urls.short_code and retried.
+type RandomGenerator struct {
+	length int
+}
+
+func NewRandomGenerator(length int) *RandomGenerator {
+	return &RandomGenerator{length: length}
+}
+
+func (g *RandomGenerator) Generate(_ context.Context, url *model.URL, _ int) error {
+	const op = "service.RandomGenerator.Generate"
+
+	code := make([]byte, g.length)
+	for i := range code {
+		n, err := randomInt(len(base62Chars))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		code[i] = base62Chars[n]
+	}
+
+	url.ShortCode = string(code)
+	return nil
+}
+
+// HashGenerator derives the code from the canonical destination, so the
+// same URL, however it is spelled, gets the same code on the first attempt.
+// Retries are deterministic too: attempt n hashes the URL followed by "#n",
+// so a taken code is followed by the same sequence of candidates every
+// time.
+type HashGenerator struct {
+	length int
+}
+
+func NewHashGenerator(length int) *HashGenerator {
+	return &HashGenerator{length: length}
+}
+
+func (g *HashGenerator) Generate(_ context.Context, url *model.URL, attempt int) error {
+	input := url.CanonicalURL
+	if attempt > 0 {
+		input += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+	value := binary.BigEndian.Uint64(sum[:8])
+
+	code := make([]byte, g.length)
+	for i := range code {
+		code[i] = base62Chars[value%uint64(len(base62Chars))]
+		value /= uint64(len(base62Chars))
+	}
+
+	url.ShortCode = string(code)
+	return nil
+}
+
+// WordsGenerator joins random words into codes such as "calm-otter-river"
+// that are easy to read out and type.
+type WordsGenerator struct {
+	count int
+}
+
+func NewWordsGenerator(count int) *WordsGenerator {
+	return &WordsGenerator{count: count}
+}
+
+func (g *WordsGenerator) Generate(_ context.Context, url *model.URL, _ int) error {
+	const op = "service.WordsGenerator.Generate"
+
+	words := make([]string, g.count)
+	for i := range words {
+		list := nouns
+		if i < g.count-1 {
+			list = adjectives
+		}
+		n, err := randomInt(len(list))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		words[i] = list[n]
+	}
+
+	url.ShortCode = strings.Join(words, "-")
+	return nil
+}
+
+func randomInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+var (
+	adjectives = []string{
+		"amber", "bold", "brave", "brisk", "calm", "clever", "cosy", "crisp",
+		"daring", "eager", "early", "fancy", "fast", "fierce", "fluffy", "gentle",
+		"giant", "glad", "golden", "grand", "happy", "hidden", "humble", "jolly",
+		"keen", "kind", "lazy", "lively", "lucky", "mellow", "merry", "mighty",
+		"misty", "noble", "odd", "plain", "polite", "proud", "quick", "quiet",
+		"rapid", "rare", "rosy", "rustic", "shiny", "silent", "silver", "sleepy",
+		"smart", "snowy", "solid", "sunny", "swift", "tidy", "tiny", "true",
+		"vivid", "warm", "wild", "wise", "witty", "young", "zany", "zesty",
+	}
+	nouns = []string{
+		"acorn", "apple", "badger", "beacon", "bear", "breeze", "brook", "cactus",
+		"canyon", "cedar", "cloud", "comet", "coral", "crane", "delta", "dune",
+		"eagle", "ember", "falcon", "fern", "finch", "forest", "fox", "garden",
+		"glacier", "harbor", "hawk", "heron", "island", "jungle", "koala", "lagoon",
+		"lake", "lantern", "lemon", "lotus", "maple", "meadow", "moon", "moose",
+		"nebula", "oak", "ocean", "orchid", "otter", "owl", "panda", "pebble",
+		"pine", "planet", "pond", "raven", "reef", "river", "robin", "sparrow",
+		"spruce", "star", "storm", "tiger", "tulip", "valley", "walrus", "willow",
+	}
+)
@@ -0,0 +1,214 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"url_shortener/internal/model"
+	"url_shortener/internal/service"
+	"url_shortener/internal/utils/shortcode"
+	"url_shortener/internal/utils/urlnorm"
+)
+
+type fakeCounter struct {
+	mu    sync.Mutex
+	value int
+}
+
+func (f *fakeCounter) Incr(ctx context.Context) (int, error) {
+	return f.IncrBy(ctx, 1)
+}
+
+func (f *fakeCounter) IncrBy(_ context.Context, n int) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value += n
+	return f.value, nil
+}
+
+func (f *fakeCounter) Reseed(_ context.Context, floor int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.value = max(f.value, floor)
+	return nil
+}
+
+type fakeCounterFloor struct {
+	floor     int
+	uncounted []model.URL
+}
+
+func (f *fakeCounterFloor) MaxCounter(context.Context) (int, error) {
+	return f.floor, nil
+}
+
+func (f *fakeCounterFloor) ListUncounted(_ context.Context, afterID, limit int) ([]model.URL, error) {
+	var urls []model.URL
+	for _, url := range f.uncounted {
+		if url.ID > afterID && len(urls) < limit {
+			urls = append(urls, url)
+		}
+	}
+	return urls, nil
+}
+
+var (
+	base62Code = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+	wordsCode  = regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`)
+)
+
+func TestCounterGenerator(t *testing.T) {
+	codec := shortcode.New("", 6)
+	generator := service.NewCounterGenerator(codec, &fakeCounter{}, &fakeCounterFloor{})
+	ctx := context.Background()
+
+	for want := 1; want <= 3; want++ {
+		url := &model.URL{}
+		if err := generator.Generate(ctx, url, 0); err != nil {
+			t.Fatal(err)
+		}
+		if url.Counter == nil || *url.Counter != want {
+			t.Fatalf("Counter = %v, want %d", url.Counter, want)
+		}
+		if num, ok := codec.Decode(url.ShortCode); !ok || num != want {
+			t.Fatalf("code %q decodes to %d, want %d", url.ShortCode, num, want)
+		}
+	}
+
+	urls := []*model.URL{{}, {}, {}}
+	if err := generator.GenerateBatch(ctx, urls); err != nil {
+		t.Fatal(err)
+	}
+	for i, url := range urls {
+		if want := 4 + i; url.Counter == nil || *url.Counter != want {
+			t.Fatalf("batch item %d: Counter = %v, want %d", i, url.Counter, want)
+		}
+	}
+}
+
+func TestCounterGeneratorReconcile(t *testing.T) {
+	codec := shortcode.New("", 6)
+	imported, _ := codec.Encode(5000)
+	counter := &fakeCounter{}
+	generator := service.NewCounterGenerator(codec, counter, &fakeCounterFloor{
+		floor: 1200,
+		uncounted: []model.URL{
+			{ID: 1, ShortCode: "my-link"},
+			{ID: 2, ShortCode: imported},
+		},
+	})
+
+	if err := generator.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if counter.value != 5000 {
+		t.Fatalf("counter reseeded to %d, want past the imported code at 5000", counter.value)
+	}
+}
+
+func TestRandomGenerator(t *testing.T) {
+	generator := service.NewRandomGenerator(8)
+
+	seen := make(map[string]struct{})
+	for range 100 {
+		url := &model.URL{}
+		if err := generator.Generate(context.Background(), url, 0); err != nil {
+			t.Fatal(err)
+		}
+		if len(url.ShortCode) != 8 || !base62Code.MatchString(url.ShortCode) {
+			t.Fatalf("code %q, want 8 base62 characters", url.ShortCode)
+		}
+		seen[url.ShortCode] = struct{}{}
+	}
+	if len(seen) < 100 {
+		t.Fatalf("%d distinct codes out of 100", len(seen))
+	}
+}
+
+func TestHashGenerator(t *testing.T) {
+	generator := service.NewHashGenerator(8)
+	normalizer := urlnorm.New([]string{"http", "https"}, false)
+	ctx := context.Background()
+
+	generate := func(raw string, attempt int) string {
+		t.Helper()
+		canonicalURL, err := normalizer.Normalize(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		url := &model.URL{OriginalURL: raw, CanonicalURL: canonicalURL}
+		if err := generator.Generate(ctx, url, attempt); err != nil {
+			t.Fatal(err)
+		}
+		if len(url.ShortCode) != 8 || !base62Code.MatchString(url.ShortCode) {
+			t.Fatalf("code %q, want 8 base62 characters", url.ShortCode)
+		}
+		return url.ShortCode
+	}
+
+	first := generate("http://example.com/a", 0)
+	if got := generate("HTTP://Example.com:80/a/../a", 0); got != first {
+		t.Fatalf("equivalent spelling got %q, want %q", got, first)
+	}
+	if got := generate("http://example.com/b", 0); got == first {
+		t.Fatalf("another url got the same code %q", got)
+	}
+
+	// Retries walk the same distinct sequence every time.
+	seen := map[string]struct{}{first: {}}
+	for attempt := 1; attempt < 5; attempt++ {
+		code := generate("http://example.com/a", attempt)
+		if again := generate("HTTP://EXAMPLE.COM/a", attempt); again != code {
+			t.Fatalf("attempt %d got %q and %q", attempt, code, again)
+		}
+		if _, ok := seen[code]; ok {
+			t.Fatalf("attempt %d repeats code %q", attempt, code)
+		}
+		seen[code] = struct{}{}
+	}
+}
+
+func TestWordsGenerator(t *testing.T) {
+	for _, count := range []int{2, 3, 5} {
+		generator := service.NewWordsGenerator(count)
+
+		url := &model.URL{}
+		if err := generator.Generate(context.Background(), url, 0); err != nil {
+			t.Fatal(err)
+		}
+		if !wordsCode.MatchString(url.ShortCode) || strings.Count(url.ShortCode, "-") != count-1 {
+			t.Fatalf("code %q, want %d lowercase words joined by '-'", url.ShortCode, count)
+		}
+	}
+}
+
+func TestCounterGeneratorCheckAlias(t *testing.T) {
+	tests := []struct {
+		alias    string
+		floor    int
+		rejected bool
+	}{
+		// Plain base62 values far past the counter.
+		{"summer", 0, false},
+		{"promo2024", 0, false},
+		// Not a code the counter can generate.
+		{"my-link", 0, false},
+		{"my_link", 0, false},
+		// 140716, among the next values.
+		{"abc", 0, true},
+		{"abc", 140715, true},
+		// Already past, taken or skipped.
+		{"abc", 140716, false},
+	}
+	for _, tt := range tests {
+		generator := service.NewCounterGenerator(shortcode.New("", 6), &fakeCounter{}, &fakeCounterFloor{floor: tt.floor})
+
+		err := generator.CheckAlias(context.Background(), tt.alias)
+		if rejected := errors.Is(err, model.ErrInvalidInput); rejected != tt.rejected {
+			t.Fatalf("CheckAlias(%s) with floor %d = %v, want rejected %v", tt.alias, tt.floor, err, tt.rejected)
+		}
+	}
+}
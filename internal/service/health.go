@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"url_shortener/internal/model"
+	"url_shortener/internal/utils/breaker"
+)
+
+type Health struct {
+	databaseBreaker DatabaseBreaker
+	urlCache        URLCache
+}
+
+func NewHealth(
+	databaseBreaker DatabaseBreaker,
+	urlCache URLCache,
+) *Health {
+	return &Health{
+		databaseBreaker: databaseBreaker,
+		urlCache:        urlCache,
+	}
+}
+
+// Check reports the instance as degraded while the database breaker isn't
+// closed: redirects of cached links work, everything else may not.
+func (h *Health) Check(_ context.Context) *model.Health {
+	health := &model.Health{
+		Status:   model.HealthOK,
+		Database: h.databaseBreaker.Stats(),
+		Cache:    h.urlCache.Stats(),
+	}
+	if health.Database.State != string(breaker.Closed) {
+		health.Status = model.HealthDegraded
+	}
+	return health
+}
@@ -2,14 +2,85 @@ package service
 
 import (
 	"context"
+	"time"
 	"url_shortener/internal/model"
 )
 
 type URLRepository interface {
-	Create(ctx context.Context, shortCode, originalURL string) (*model.URL, error)
-	GetOriginalURLByShortCode(ctx context.Context, shortCode string) (string, error)
+	Create(ctx context.Context, url *model.URL) (*model.URL, error)
+	CreateBatch(ctx context.Context, urls []*model.URL) ([]model.URL, error)
+	GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error)
+	GetLatestByCanonicalURL(ctx context.Context, ownerID *string, canonicalURL string) (*model.URL, error)
+	Update(ctx context.Context, shortCode, originalURL, canonicalURL string) (*model.URL, error)
+	Delete(ctx context.Context, shortCode string) error
+	List(ctx context.Context, filter model.URLFilter) ([]model.URL, error)
 }
 
 type CounterRepository interface {
 	Incr(ctx context.Context) (int, error)
+	IncrBy(ctx context.Context, n int) (int, error)
+	Reseed(ctx context.Context, floor int) error
+}
+
+type CounterFloorRepository interface {
+	MaxCounter(ctx context.Context) (int, error)
+	ListUncounted(ctx context.Context, afterID, limit int) ([]model.URL, error)
+}
+
+type CanonicalURLRepository interface {
+	ListUncanonical(ctx context.Context, afterID, limit int) ([]model.URL, error)
+	UpdateCanonicalURL(ctx context.Context, id int, canonicalURL string) error
+}
+
+type URLNormalizer interface {
+	Normalize(raw string) (string, error)
+}
+
+type URLPolicy interface {
+	Check(ctx context.Context, canonicalURL string) error
+}
+
+type BlocklistRepository interface {
+	Match(host, rawURL string) (string, bool)
+}
+
+type DestinationValidator interface {
+	Validate(ctx context.Context, host string) error
+}
+
+type ShortCodeCodec interface {
+	Encode(num int) (string, error)
+	Decode(code string) (int, bool)
+}
+
+type ClickQueue interface {
+	Enqueue(ctx context.Context, click model.Click) bool
+}
+
+type ClickRepository interface {
+	GetSeries(ctx context.Context, urlID int, granularity string, from, to time.Time) ([]model.ClickPoint, error)
+	CountUniqueVisitors(ctx context.Context, urlID int, from, to time.Time) (int64, error)
+	GetTopValues(
+		ctx context.Context,
+		urlID int,
+		dimension string,
+		from, to time.Time,
+		limit int,
+	) ([]model.ClickCount, error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, apiKey *model.APIKey) (*model.APIKey, error)
+	GetActiveByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+	GetByID(ctx context.Context, id int) (*model.APIKey, error)
+	List(ctx context.Context, ownerID *string) ([]model.APIKey, error)
+	Revoke(ctx context.Context, id int) (*model.APIKey, error)
+}
+
+type DatabaseBreaker interface {
+	Stats() model.BreakerStats
+}
+
+type URLCache interface {
+	Stats() model.CacheStats
 }
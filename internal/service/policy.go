@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"url_shortener/internal/model"
+	"url_shortener/internal/utils/ssrf"
+)
+
+// PolicyRule vets the destination of a link. A rule rejects it by returning
+// a model.ErrUnprocessable error whose message is the reason shown to the
+// caller, other errors fail the create.
+type PolicyRule interface {
+	Check(ctx context.Context, destination *url.URL) error
+}
+
+// Policy runs every rule against canonical destinations on create and
+// update, stopping at the first rejection.
+type Policy struct {
+	rules []PolicyRule
+}
+
+func NewPolicy(rules ...PolicyRule) *Policy {
+	return &Policy{rules: rules}
+}
+
+func (p *Policy) Check(ctx context.Context, canonicalURL string) error {
+	const op = "service.Policy.Check"
+
+	destination, err := url.Parse(canonicalURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput, "invalid url"))
+	}
+
+	for _, rule := range p.rules {
+		if err := rule.Check(ctx, destination); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// SelfDomainRule rejects links to the domains this service is served on,
+// which would redirect to another short link or loop forever.
+type SelfDomainRule struct {
+	domains []string
+}
+
+func NewSelfDomainRule(domains []string) *SelfDomainRule {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		normalized = append(normalized, strings.TrimSuffix(strings.ToLower(domain), "."))
+	}
+	return &SelfDomainRule{domains: normalized}
+}
+
+func (r *SelfDomainRule) Check(_ context.Context, destination *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(destination.Hostname()), ".")
+	for _, domain := range r.domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return model.NewError(model.ErrUnprocessable,
+				fmt.Sprintf("links to %s are not allowed, it is served by this shortener", host))
+		}
+	}
+	return nil
+}
+
+// BlocklistRule rejects destinations matched by the blocklist.
+type BlocklistRule struct {
+	blocklistRepository BlocklistRepository
+}
+
+func NewBlocklistRule(
+	blocklistRepository BlocklistRepository,
+) *BlocklistRule {
+	return &BlocklistRule{
+		blocklistRepository: blocklistRepository,
+	}
+}
+
+func (r *BlocklistRule) Check(_ context.Context, destination *url.URL) error {
+	rule, ok := r.blocklistRepository.Match(destination.Hostname(), destination.String())
+	if ok {
+		return model.NewError(model.ErrUnprocessable,
+			fmt.Sprintf("destination is blocklisted by rule %q", rule))
+	}
+	return nil
+}
+
+// DestinationRule resolves the destination host and rejects it when it
+// points into private, loopback, link-local or metadata address space, so
+// links can't be used to reach internal services through anything that
+// follows them server side. A host that doesn't exist is rejected too, while
+// a lookup that fails or times out is reported as unavailable so the caller
+// retries.
+type DestinationRule struct {
+	timeout              time.Duration
+	destinationValidator DestinationValidator
+}
+
+func NewDestinationRule(
+	timeout time.Duration,
+	destinationValidator DestinationValidator,
+) *DestinationRule {
+	return &DestinationRule{
+		timeout:              timeout,
+		destinationValidator: destinationValidator,
+	}
+}
+
+func (r *DestinationRule) Check(ctx context.Context, destination *url.URL) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	host := destination.Hostname()
+	err := r.destinationValidator.Validate(ctx, host)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ssrf.ErrBlockedAddress):
+		return model.NewError(model.ErrUnprocessable,
+			fmt.Sprintf("%s resolves to a non-public address", host))
+	case errors.Is(err, ssrf.ErrNoAddress), isNotFound(err):
+		return model.NewError(model.ErrUnprocessable,
+			fmt.Sprintf("%s could not be resolved", host))
+	default:
+		return model.NewError(model.ErrUnavailable,
+			fmt.Sprintf("%s could not be resolved: %v", host, err))
+	}
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
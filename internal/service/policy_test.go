@@ -0,0 +1,80 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"url_shortener/internal/handler/helper"
+	"url_shortener/internal/handler/response"
+	"url_shortener/internal/model"
+	"url_shortener/internal/repository/file"
+	"url_shortener/internal/service"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func newPolicy(t *testing.T) *service.Policy {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("evil.com\nregex:/phish\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return service.NewPolicy(
+		service.NewSelfDomainRule([]string{"Sho.rt."}),
+		service.NewBlocklistRule(file.MustNewBlocklist(path)),
+	)
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := newPolicy(t)
+
+	tests := []struct {
+		canonicalURL string
+		reason       string
+	}{
+		{"https://example.com/", ""},
+		{"https://notevil.com/", ""},
+		{"https://evil.com.example.net/", ""},
+		{"https://notsho.rt/", ""},
+		{"https://evil.com/", `rule "evil.com"`},
+		{"https://www.evil.com/a", `rule "evil.com"`},
+		{"https://EVIL.COM./", `rule "evil.com"`},
+		{"https://example.com/phish", `rule "regex:/phish"`},
+		{"https://sho.rt/abc", "served by this shortener"},
+		{"https://www.SHO.RT./abc", "served by this shortener"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.canonicalURL, func(t *testing.T) {
+			err := policy.Check(context.Background(), tt.canonicalURL)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("Check(%s) = %v, want nil", tt.canonicalURL, err)
+				}
+				return
+			}
+			if !errors.Is(err, model.ErrUnprocessable) || !strings.Contains(err.Error(), tt.reason) {
+				t.Fatalf("Check(%s) = %v, want unprocessable with %s", tt.canonicalURL, err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestPolicyRejectionIsUnprocessable(t *testing.T) {
+	helper.Setup(slog.New(slog.NewTextHandler(io.Discard, nil)), validator.New())
+
+	err := newPolicy(t).Check(context.Background(), "https://login.evil.com/")
+	status, fail := helper.Failure(err)
+	if status != http.StatusUnprocessableEntity || fail.Code != response.CodeUnprocessable {
+		t.Fatalf("Failure() = %d %s, want 422 %s", status, fail.Code, response.CodeUnprocessable)
+	}
+	if want := `destination is blocklisted by rule "evil.com"`; fail.Error != want {
+		t.Fatalf("error = %q, want %q", fail.Error, want)
+	}
+}
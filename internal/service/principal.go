@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"url_shortener/internal/model"
+)
+
+// creatorID returns the owner recorded on a new link: the caller's owner,
+// nil for the admin, or nil for anonymous callers when those are allowed.
+func creatorID(ctx context.Context, anonymous bool) (*string, error) {
+	principal, ok := model.PrincipalFrom(ctx)
+	if !ok {
+		if anonymous {
+			return nil, nil //nolint:nilnil // anonymous link has no owner
+		}
+		return nil, model.ErrUnauthorized
+	}
+	if principal.Admin {
+		return nil, nil //nolint:nilnil // admin links have no owner
+	}
+	return &principal.OwnerID, nil
+}
+
+// ownerScope returns the owner management queries are limited to, or nil
+// for the admin who sees every link.
+func ownerScope(ctx context.Context) (*string, error) {
+	principal, ok := model.PrincipalFrom(ctx)
+	if !ok {
+		return nil, model.ErrUnauthorized
+	}
+	if principal.Admin {
+		return nil, nil //nolint:nilnil // admin is not scoped
+	}
+	return &principal.OwnerID, nil
+}
+
+func authorize(ctx context.Context, url *model.URL) error {
+	ownerID, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+	if ownerID == nil {
+		return nil
+	}
+	if url.OwnerID == nil || *url.OwnerID != *ownerID {
+		return model.ErrForbidden
+	}
+	return nil
+}
@@ -2,60 +2,823 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 	"url_shortener/internal/model"
-	"url_shortener/internal/utils/base62"
+	"url_shortener/internal/utils/linkfile"
+	"url_shortener/internal/utils/urlnorm"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// maxGenerateAttempts bounds how many codes Create tries when a generated
+// code is already taken, by a custom alias, another strategy or a code
+// minted before the short code key changed.
+const maxGenerateAttempts = 10
+
+const (
+	// exportPageSize is how many links Export loads per query.
+	exportPageSize = 1000
+	// importBatchSize is how many rows Import inserts per statement.
+	importBatchSize = 500
+	// prepareConcurrency is how many items of a batch CreateBatch checks at
+	// once, since the policy may resolve each destination.
+	prepareConcurrency = 8
+)
+
+var (
+	aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
+	// importedCodePattern is looser than aliasPattern since other
+	// shorteners mint shorter codes.
+	importedCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+	reservedAliases = []string{"urls", "swagger", "health", "api-keys", "export"}
 )
 
 type URL struct {
+	anonymousCreate   bool
+	defaultStrategy   string
+	requestStrategies []string
+	maxBatchSize      int
+	generators        map[string]ShortCodeGenerator
+	urlNormalizer     URLNormalizer
+	urlPolicy         URLPolicy
 	urlRepository     URLRepository
-	counterRepository CounterRepository
 }
 
+// NewURL takes the generator of every strategy, the one used when the
+// caller doesn't pick one, and the strategies api keys may pick per
+// request; the admin may pick any and anonymous callers none.
+// maxBatchSize caps the items of one CreateBatch.
 func NewURL(
+	anonymousCreate bool,
+	defaultStrategy string,
+	requestStrategies []string,
+	maxBatchSize int,
+	generators map[string]ShortCodeGenerator,
+	urlNormalizer URLNormalizer,
+	urlPolicy URLPolicy,
 	urlRepository URLRepository,
-	counterRepository CounterRepository,
 ) *URL {
 	return &URL{
+		anonymousCreate:   anonymousCreate,
+		defaultStrategy:   defaultStrategy,
+		requestStrategies: requestStrategies,
+		maxBatchSize:      maxBatchSize,
+		generators:        generators,
+		urlNormalizer:     urlNormalizer,
+		urlPolicy:         urlPolicy,
 		urlRepository:     urlRepository,
-		counterRepository: counterRepository,
 	}
 }
 
-func (u *URL) Create(ctx context.Context, originalURL string) (*model.URL, error) {
+// Create returns the new link and true, or the caller's existing link to
+// the same destination and false when input.ReuseExisting found one.
+func (u *URL) Create(ctx context.Context, input model.CreateURL) (*model.URL, bool, error) {
 	const op = "service.URL.Create"
 
-	shortCode, err := u.generateShortCode(ctx)
+	ownerID, err := creatorID(ctx, u.anonymousCreate)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, generator, existing, err := u.prepare(ctx, ownerID, input)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	if input.Alias != "" {
+		created, err := u.createWithAlias(ctx, url)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", op, err)
+		}
+		return created, true, nil
+	}
+
+	created, err := u.createGenerated(ctx, url, generator, 0)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, true, nil
+}
+
+// CreateBatch creates every input like Create and reports each outcome at
+// the index of its input; one bad item never fails the others. Counter
+// codes are allocated with one counter call and all links are inserted
+// with one statement, items whose code turns out to be taken then take
+// the one by one path.
+func (u *URL) CreateBatch(ctx context.Context, inputs []model.CreateURL) ([]model.CreateURLResult, error) {
+	const op = "service.URL.CreateBatch"
+
+	if len(inputs) == 0 || len(inputs) > u.maxBatchSize {
+		return nil, fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput,
+			fmt.Sprintf("batch must have 1 to %d items", u.maxBatchSize)))
+	}
+
+	ownerID, err := creatorID(ctx, u.anonymousCreate)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	url, err := u.urlRepository.Create(ctx, shortCode, originalURL)
+	results := make([]model.CreateURLResult, len(inputs))
+	urls := make([]*model.URL, len(inputs))
+	generators := make([]ShortCodeGenerator, len(inputs))
+	var (
+		generatorOrder []ShortCodeGenerator
+		byGenerator    = make(map[ShortCodeGenerator][]int)
+	)
+	for i, prepared := range u.prepareBatch(ctx, ownerID, inputs) {
+		generator := prepared.generator
+		switch {
+		case prepared.err != nil:
+			results[i].Err = fmt.Errorf("%s: %w", op, prepared.err)
+		case prepared.existing != nil:
+			results[i].URL = prepared.existing
+		case inputs[i].Alias != "":
+			urls[i] = prepared.url
+		default:
+			urls[i], generators[i] = prepared.url, generator
+			if _, ok := byGenerator[generator]; !ok {
+				generatorOrder = append(generatorOrder, generator)
+			}
+			byGenerator[generator] = append(byGenerator[generator], i)
+		}
+	}
+
+	for _, generator := range generatorOrder {
+		for i, err := range generateBatch(ctx, generator, urls, byGenerator[generator]) {
+			results[i].Err = fmt.Errorf("%s: %w", op, err)
+			urls[i] = nil
+		}
+	}
+
+	var (
+		batch   []*model.URL
+		indexes []int
+		retry   []int
+		seen    = make(map[string]struct{})
+	)
+	for i, url := range urls {
+		if url == nil {
+			continue
+		}
+		if _, ok := seen[url.ShortCode]; ok {
+			retry = append(retry, i)
+			continue
+		}
+		seen[url.ShortCode] = struct{}{}
+		batch = append(batch, url)
+		indexes = append(indexes, i)
+	}
+
+	created, err := u.urlRepository.CreateBatch(ctx, batch)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	byShortCode := make(map[string]*model.URL, len(created))
+	for i := range created {
+		byShortCode[created[i].ShortCode] = &created[i]
+	}
+	for _, i := range indexes {
+		if url, ok := byShortCode[urls[i].ShortCode]; ok {
+			results[i] = model.CreateURLResult{URL: url, Created: true}
+			continue
+		}
+		retry = append(retry, i)
+	}
+	slices.Sort(retry)
 
-	return url, nil
+	reconciled := make(map[ShortCodeGenerator]bool)
+	for _, i := range retry {
+		generator := generators[i]
+		if generator == nil {
+			results[i].Err = fmt.Errorf("%s: %w", op, model.NewError(model.ErrConflict, "alias already taken"))
+			continue
+		}
+
+		if reconciler, ok := generator.(conflictReconciler); ok && urls[i].Counter != nil && !reconciled[generator] {
+			reconciled[generator] = true
+			if err := reconciler.Reconcile(ctx); err != nil {
+				results[i].Err = fmt.Errorf("%s: %w", op, err)
+				continue
+			}
+		}
+
+		url, err := u.createGenerated(ctx, urls[i], generator, 1)
+		if err != nil {
+			results[i].Err = fmt.Errorf("%s: %w", op, err)
+			continue
+		}
+		results[i] = model.CreateURLResult{URL: url, Created: true}
+	}
+
+	return results, nil
+}
+
+// Import creates the links of a CSV or JSONL file with the short codes
+// they had elsewhere. A code that is already taken is a conflict, never
+// overwritten, and like any bad row it is reported without stopping the
+// import. Only the admin may set owners, links of an api key belong to its
+// owner. Codes the counter could have produced are recorded with their
+// counter value and the counter is moved past them, so it never hands
+// them out again.
+func (u *URL) Import(ctx context.Context, r io.Reader, format string) (*model.ImportReport, error) {
+	const op = "service.URL.Import"
+
+	scope, err := ownerScope(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	reader, err := linkfile.NewReader(r, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput, err.Error()))
+	}
+
+	var (
+		report  model.ImportReport
+		counted bool
+		inputs  = make([]model.ImportURL, 0, importBatchSize)
+		lines   = make([]int, 0, importBatchSize)
+		lastRow int
+	)
+	flush := func() error {
+		results, batchCounted, err := u.importBatch(ctx, scope, inputs)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			if result.Err != nil {
+				report.Failed = append(report.Failed, model.ImportFailure{
+					Line:      lines[i],
+					ShortCode: inputs[i].ShortCode,
+					Err:       result.Err,
+				})
+				continue
+			}
+			report.Imported++
+		}
+		counted = counted || batchCounted
+		inputs, lines = inputs[:0], lines[:0]
+		return nil
+	}
+
+	for {
+		record, line, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *linkfile.RowError
+		if errors.As(err, &rowErr) {
+			lastRow = rowErr.Line
+			report.Failed = append(report.Failed, model.ImportFailure{
+				Line: rowErr.Line,
+				Err:  model.NewError(model.ErrInvalidInput, rowErr.Err.Error()),
+			})
+			continue
+		}
+		if err != nil {
+			if lastRow == 0 {
+				return nil, fmt.Errorf("%s: %w", op, model.NewError(model.ErrInvalidInput, err.Error()))
+			}
+			report.Failed = append(report.Failed, model.ImportFailure{
+				Line: lastRow + 1,
+				Err:  model.NewError(model.ErrInvalidInput, "rest of the file is unreadable: "+err.Error()),
+			})
+			break
+		}
+		lastRow = line
+
+		input := model.ImportURL{
+			ShortCode:   record.ShortCode,
+			OriginalURL: record.OriginalURL,
+			ExpiresAt:   record.ExpiresAt,
+		}
+		if record.OwnerID != "" {
+			input.OwnerID = &record.OwnerID
+		}
+		inputs = append(inputs, input)
+		lines = append(lines, line)
+
+		if len(inputs) == importBatchSize {
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+	if len(inputs) > 0 {
+		if err := flush(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	slices.SortStableFunc(report.Failed, func(a, b model.ImportFailure) int {
+		return a.Line - b.Line
+	})
+
+	if counted {
+		for _, generator := range u.generators {
+			if reconciler, ok := generator.(conflictReconciler); ok {
+				if err := reconciler.Reconcile(ctx); err != nil {
+					return nil, fmt.Errorf("%s: %w", op, err)
+				}
+			}
+		}
+	}
+
+	return &report, nil
+}
+
+// Export passes the links visible to the caller matching filter to fn, one
+// page at a time and newest first, so any number of them can be streamed.
+// filter.Limit and filter.AfterID are ignored.
+func (u *URL) Export(ctx context.Context, filter model.URLFilter, fn func(urls []model.URL) error) error {
+	const op = "service.URL.Export"
+
+	ownerID, err := ownerScope(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	filter.OwnerID = ownerID
+	filter.AfterID = 0
+	filter.Limit = exportPageSize
+
+	for {
+		urls, err := u.urlRepository.List(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(urls) == 0 {
+			return nil
+		}
+
+		if err := fn(urls); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if len(urls) < exportPageSize {
+			return nil
+		}
+		filter.AfterID = urls[len(urls)-1].ID
+	}
 }
 
 func (u *URL) GetOriginalURL(ctx context.Context, shortCode string) (string, error) {
 	const op = "service.URL.GetOriginalURL"
 
-	originalURL, err := u.urlRepository.GetOriginalURLByShortCode(ctx, shortCode)
+	url, err := u.urlRepository.GetByShortCode(ctx, shortCode)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return originalURL, nil
+	if url.IsExpired(time.Now()) {
+		return "", fmt.Errorf("%s: %w", op, model.NewError(model.ErrGone, "link has expired"))
+	}
+
+	return url.OriginalURL, nil
 }
 
-func (u *URL) generateShortCode(ctx context.Context) (string, error) {
-	const op = "service.URL.generateShortCode"
+func (u *URL) Get(ctx context.Context, shortCode string) (*model.URL, error) {
+	const op = "service.URL.Get"
 
-	num, err := u.counterRepository.Incr(ctx)
+	url, err := u.getOwned(ctx, shortCode)
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return base62.Encode(num), nil
+	return url, nil
+}
+
+func (u *URL) Update(ctx context.Context, shortCode, originalURL string) (*model.URL, error) {
+	const op = "service.URL.Update"
+
+	canonicalURL, err := u.normalize(originalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := u.getOwned(ctx, shortCode); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := u.urlPolicy.Check(ctx, canonicalURL); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	url, err := u.urlRepository.Update(ctx, shortCode, originalURL, canonicalURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+func (u *URL) Delete(ctx context.Context, shortCode string) error {
+	const op = "service.URL.Delete"
+
+	if _, err := u.getOwned(ctx, shortCode); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := u.urlRepository.Delete(ctx, shortCode); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// List returns one page of links, newest first, and the cursor of the next
+// page, which is empty on the last page.
+func (u *URL) List(ctx context.Context, filter model.URLFilter, cursor string) ([]model.URL, string, error) {
+	const op = "service.URL.List"
+
+	ownerID, err := ownerScope(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	filter.OwnerID = ownerID
+
+	if cursor != "" {
+		afterID, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+		filter.AfterID = afterID
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+
+	urls, err := u.urlRepository.List(ctx, filter)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(urls) <= limit {
+		return urls, "", nil
+	}
+
+	urls = urls[:limit]
+	return urls, encodeCursor(urls[limit-1].ID), nil
+}
+
+// getOwned loads a link for a management operation on behalf of the caller.
+func (u *URL) getOwned(ctx context.Context, shortCode string) (*model.URL, error) {
+	const op = "service.URL.getOwned"
+
+	url, err := u.urlRepository.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := authorize(ctx, url); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return url, nil
+}
+
+// prepare checks input and builds the link to insert along with the
+// generator of its code, or returns the existing link to reuse instead.
+func (u *URL) prepare(
+	ctx context.Context,
+	ownerID *string,
+	input model.CreateURL,
+) (*model.URL, ShortCodeGenerator, *model.URL, error) {
+	generator, err := u.generator(ctx, input.Alias, input.Strategy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if input.Alias != "" {
+		if err := u.validateAlias(ctx, input.Alias); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	expiresAt, err := resolveExpiresAt(time.Now(), input.ExpiresAt, input.ExpiresIn)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	canonicalURL, err := u.normalize(input.OriginalURL)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := u.urlPolicy.Check(ctx, canonicalURL); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if input.ReuseExisting {
+		if input.Alias != "" {
+			return nil, nil, nil, model.NewError(model.ErrInvalidInput, "alias and reuse_existing are mutually exclusive")
+		}
+
+		existing, err := u.urlRepository.GetLatestByCanonicalURL(ctx, ownerID, canonicalURL)
+		if err == nil {
+			return nil, nil, existing, nil
+		}
+		if !errors.Is(err, model.ErrNotFound) {
+			return nil, nil, nil, err
+		}
+	}
+
+	url := &model.URL{
+		ShortCode:    input.Alias,
+		OriginalURL:  input.OriginalURL,
+		CanonicalURL: canonicalURL,
+		ExpiresAt:    expiresAt,
+		OwnerID:      ownerID,
+	}
+	return url, generator, nil, nil
+}
+
+type preparedURL struct {
+	url       *model.URL
+	generator ShortCodeGenerator
+	existing  *model.URL
+	err       error
+}
+
+// prepareBatch runs prepare for every input, prepareConcurrency at a time,
+// and reports each outcome at the index of its input.
+func (u *URL) prepareBatch(ctx context.Context, ownerID *string, inputs []model.CreateURL) []preparedURL {
+	prepared := make([]preparedURL, len(inputs))
+
+	var group errgroup.Group
+	group.SetLimit(prepareConcurrency)
+	for i, input := range inputs {
+		group.Go(func() error {
+			p := &prepared[i]
+			p.url, p.generator, p.existing, p.err = u.prepare(ctx, ownerID, input)
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	return prepared
+}
+
+// importBatch inserts inputs with one statement and reports each outcome at
+// the index of its input, and whether any inserted link has a counter
+// value.
+func (u *URL) importBatch(
+	ctx context.Context,
+	scope *string,
+	inputs []model.ImportURL,
+) ([]model.CreateURLResult, bool, error) {
+	const op = "service.URL.importBatch"
+
+	results := make([]model.CreateURLResult, len(inputs))
+	var (
+		batch   []*model.URL
+		indexes []int
+		seen    = make(map[string]struct{})
+	)
+	for i, input := range inputs {
+		url, err := u.prepareImport(ctx, scope, input)
+		if err != nil {
+			results[i].Err = fmt.Errorf("%s: %w", op, err)
+			continue
+		}
+		if _, ok := seen[url.ShortCode]; ok {
+			results[i].Err = fmt.Errorf("%s: %w", op, model.NewError(model.ErrConflict, "short code already taken"))
+			continue
+		}
+		seen[url.ShortCode] = struct{}{}
+		batch = append(batch, url)
+		indexes = append(indexes, i)
+	}
+
+	created, err := u.urlRepository.CreateBatch(ctx, batch)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	counted := false
+	byShortCode := make(map[string]*model.URL, len(created))
+	for i := range created {
+		byShortCode[created[i].ShortCode] = &created[i]
+		counted = counted || created[i].Counter != nil
+	}
+	for j, i := range indexes {
+		url, ok := byShortCode[batch[j].ShortCode]
+		if !ok {
+			results[i].Err = fmt.Errorf("%s: %w", op, model.NewError(model.ErrConflict, "short code already taken"))
+			continue
+		}
+		results[i] = model.CreateURLResult{URL: url, Created: true}
+	}
+
+	return results, counted, nil
+}
+
+// prepareImport checks input like prepare does a create and builds the
+// link to insert.
+func (u *URL) prepareImport(ctx context.Context, scope *string, input model.ImportURL) (*model.URL, error) {
+	if !importedCodePattern.MatchString(input.ShortCode) {
+		return nil, model.NewError(model.ErrInvalidInput,
+			"short code must be 1 to 64 characters of letters, digits, '-' or '_'")
+	}
+	if slices.Contains(reservedAliases, strings.ToLower(input.ShortCode)) {
+		return nil, model.NewError(model.ErrInvalidInput, "short code is reserved")
+	}
+
+	ownerID := input.OwnerID
+	if scope != nil {
+		if ownerID != nil && *ownerID != *scope {
+			return nil, model.NewError(model.ErrForbidden, "only the admin may import links of other owners")
+		}
+		ownerID = scope
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, model.NewError(model.ErrInvalidInput, "link has already expired")
+	}
+
+	canonicalURL, err := u.normalize(input.OriginalURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.urlPolicy.Check(ctx, canonicalURL); err != nil {
+		return nil, err
+	}
+
+	url := &model.URL{
+		ShortCode:    input.ShortCode,
+		OriginalURL:  input.OriginalURL,
+		CanonicalURL: canonicalURL,
+		OwnerID:      ownerID,
+	}
+	if input.ExpiresAt != nil {
+		expiresAt := input.ExpiresAt.UTC()
+		url.ExpiresAt = &expiresAt
+	}
+	for _, generator := range u.generators {
+		if decoder, ok := generator.(codeDecoder); ok {
+			if num, ok := decoder.Decode(url.ShortCode); ok {
+				url.Counter = &num
+			}
+		}
+	}
+
+	return url, nil
+}
+
+func (u *URL) createWithAlias(ctx context.Context, url *model.URL) (*model.URL, error) {
+	const op = "service.URL.createWithAlias"
+
+	created, err := u.urlRepository.Create(ctx, url)
+	if errors.Is(err, model.ErrConflict) {
+		return nil, fmt.Errorf("%s: %w", op, model.NewError(model.ErrConflict, "alias already taken"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return created, nil
+}
+
+// createGenerated inserts url with a generated code, generating another
+// one while the code is taken.
+func (u *URL) createGenerated(
+	ctx context.Context,
+	url *model.URL,
+	generator ShortCodeGenerator,
+	firstAttempt int,
+) (*model.URL, error) {
+	const op = "service.URL.createGenerated"
+
+	for attempt := firstAttempt; attempt < maxGenerateAttempts; attempt++ {
+		if err := generator.Generate(ctx, url, attempt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		created, err := u.urlRepository.Create(ctx, url)
+		if errors.Is(err, model.ErrConflict) {
+			// Only a taken counter code means the counter fell behind.
+			if reconciler, ok := generator.(conflictReconciler); ok && url.Counter != nil {
+				if err := reconciler.Reconcile(ctx); err != nil {
+					return nil, fmt.Errorf("%s: %w", op, err)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return created, nil
+	}
+
+	return nil, fmt.Errorf("%s: no free short code after %d attempts", op, maxGenerateAttempts)
+}
+
+// generator picks the strategy of a new link, checking that the caller may
+// choose it.
+func (u *URL) generator(ctx context.Context, alias, strategy string) (ShortCodeGenerator, error) {
+	if strategy == "" {
+		return u.generators[u.defaultStrategy], nil
+	}
+	if alias != "" {
+		return nil, model.NewError(model.ErrInvalidInput, "alias and strategy are mutually exclusive")
+	}
+
+	generator, ok := u.generators[strategy]
+	if !ok {
+		return nil, model.NewError(model.ErrInvalidInput, fmt.Sprintf("unknown strategy %q", strategy))
+	}
+
+	if strategy == u.defaultStrategy {
+		return generator, nil
+	}
+	principal, ok := model.PrincipalFrom(ctx)
+	if !ok || (!principal.Admin && !slices.Contains(u.requestStrategies, strategy)) {
+		return nil, model.NewError(model.ErrForbidden, fmt.Sprintf("strategy %q is not allowed", strategy))
+	}
+
+	return generator, nil
+}
+
+func (u *URL) normalize(originalURL string) (string, error) {
+	canonicalURL, err := u.urlNormalizer.Normalize(originalURL)
+	if errors.Is(err, urlnorm.ErrSchemeNotAllowed) {
+		return "", model.NewError(model.ErrInvalidInput, "url scheme is not allowed")
+	}
+	if err != nil {
+		return "", model.NewError(model.ErrInvalidInput, "url must be absolute with a host")
+	}
+	return canonicalURL, nil
+}
+
+// validateAlias also rejects aliases a generator is about to produce.
+func (u *URL) validateAlias(ctx context.Context, alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return model.NewError(model.ErrInvalidInput,
+			"alias must be 3 to 32 characters of letters, digits, '-' or '_'")
+	}
+	if slices.Contains(reservedAliases, strings.ToLower(alias)) {
+		return model.NewError(model.ErrInvalidInput, "alias is reserved")
+	}
+	for _, generator := range u.generators {
+		if checker, ok := generator.(aliasChecker); ok {
+			if err := checker.CheckAlias(ctx, alias); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveExpiresAt turns the absolute or relative lifetime requested by the
+// caller into the UTC timestamp stored with the link.
+func resolveExpiresAt(now time.Time, expiresAt *time.Time, expiresIn time.Duration) (*time.Time, error) {
+	if expiresAt != nil && expiresIn != 0 {
+		return nil, model.NewError(model.ErrInvalidInput, "expires_at and expires_in are mutually exclusive")
+	}
+	if expiresIn < 0 {
+		return nil, model.NewError(model.ErrInvalidInput, "expires_in must be positive")
+	}
+	if expiresIn > 0 {
+		at := now.Add(expiresIn)
+		expiresAt = &at
+	}
+	if expiresAt == nil {
+		return nil, nil //nolint:nilnil // no expiration requested
+	}
+	if !expiresAt.After(now) {
+		return nil, model.NewError(model.ErrInvalidInput, "expires_at must be in the future")
+	}
+
+	at := expiresAt.UTC()
+	return &at, nil
+}
+
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, model.NewError(model.ErrInvalidInput, "invalid cursor")
+	}
+	id, err := strconv.Atoi(string(raw))
+	if err != nil || id <= 0 {
+		return 0, model.NewError(model.ErrInvalidInput, "invalid cursor")
+	}
+	return id, nil
 }
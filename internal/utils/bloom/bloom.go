@@ -0,0 +1,60 @@
+package bloom
+
+import (
+	"hash/maphash"
+	"math"
+	"sync/atomic"
+)
+
+// Filter is a Bloom filter of strings: MayContain never misses an added
+// value and wrongly reports others at about the rate it was sized for. It
+// is safe for concurrent use.
+type Filter struct {
+	bits  []atomic.Uint64
+	m     uint64
+	k     uint64
+	seed1 maphash.Seed
+	seed2 maphash.Seed
+}
+
+// New sizes the filter for capacity values at falsePositiveRate; more
+// values raise the rate.
+func New(capacity int, falsePositiveRate float64) *Filter {
+	n := float64(max(capacity, 1))
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+
+	words := (uint64(m) + 63) / 64
+	return &Filter{
+		bits:  make([]atomic.Uint64, words),
+		m:     words * 64,
+		k:     uint64(k),
+		seed1: maphash.MakeSeed(),
+		seed2: maphash.MakeSeed(),
+	}
+}
+
+func (f *Filter) Add(value string) {
+	h1, h2 := f.hash(value)
+	for i := range f.k {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64].Or(1 << (bit % 64))
+	}
+}
+
+func (f *Filter) MayContain(value string) bool {
+	h1, h2 := f.hash(value)
+	for i := range f.k {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hash derives the k bit positions from two hashes (Kirsch-Mitzenmacher);
+// h2 is odd so the positions don't repeat early.
+func (f *Filter) hash(value string) (uint64, uint64) {
+	return maphash.String(f.seed1, value), maphash.String(f.seed2, value) | 1
+}
@@ -0,0 +1,49 @@
+package bloom_test
+
+import (
+	"strconv"
+	"testing"
+	"url_shortener/internal/utils/bloom"
+)
+
+func TestFilterHasNoFalseNegatives(t *testing.T) {
+	filter := bloom.New(10_000, 0.01)
+	for i := range 10_000 {
+		filter.Add("added-" + strconv.Itoa(i))
+	}
+	for i := range 10_000 {
+		if value := "added-" + strconv.Itoa(i); !filter.MayContain(value) {
+			t.Fatalf("MayContain(%s) = false after Add", value)
+		}
+	}
+}
+
+func TestFilterFalsePositiveRate(t *testing.T) {
+	filter := bloom.New(10_000, 0.01)
+	for i := range 10_000 {
+		filter.Add("added-" + strconv.Itoa(i))
+	}
+
+	const probes = 100_000
+	falsePositives := 0
+	for i := range probes {
+		if filter.MayContain("other-" + strconv.Itoa(i)) {
+			falsePositives++
+		}
+	}
+	// Sized for 1%, allow some slack for the hash seeds.
+	if rate := float64(falsePositives) / probes; rate > 0.02 {
+		t.Fatalf("false positive rate = %.4f, want about 0.01", rate)
+	}
+}
+
+func TestEmptyFilter(t *testing.T) {
+	filter := bloom.New(0, 0.01)
+	if filter.MayContain("anything") {
+		t.Fatal("MayContain = true on an empty filter")
+	}
+	filter.Add("anything")
+	if !filter.MayContain("anything") {
+		t.Fatal("MayContain = false after Add")
+	}
+}
@@ -0,0 +1,123 @@
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	// Closed lets every call through.
+	Closed State = "closed"
+	// Open rejects every call until the cooldown has passed.
+	Open State = "open"
+	// HalfOpen lets a single probe through, whose outcome closes the
+	// breaker or opens it again.
+	HalfOpen State = "half_open"
+)
+
+// Breaker is a circuit breaker: threshold consecutive failures open it,
+// so calls fail fast instead of piling up on a dependency that is down. A
+// threshold of zero disables it. It is safe for concurrent use.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+}
+
+// Stats is a snapshot of the breaker, OpenedAt is zero while closed.
+type Stats struct {
+	State    State
+	Failures int
+	OpenedAt time.Time
+}
+
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     Closed,
+	}
+}
+
+// Allow reports whether a call may go ahead, every allowed call must be
+// followed by Record or Abandon. Otherwise retryAfter is when a call
+// should be tried again.
+func (b *Breaker) Allow() (retryAfter time.Duration, ok bool) {
+	if b.threshold <= 0 {
+		return 0, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		return 0, true
+	case Open:
+		if wait := b.cooldown - time.Since(b.openedAt); wait > 0 {
+			return wait, false
+		}
+		b.state = HalfOpen
+		return 0, true
+	default:
+		return b.cooldown, false
+	}
+}
+
+// Record reports the outcome of an allowed call.
+func (b *Breaker) Record(failed bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.state = Closed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == HalfOpen || (b.state == Closed && b.failures >= b.threshold) {
+		b.state = Open
+		b.openedAt = time.Now()
+	}
+}
+
+// Abandon reports an allowed call that ended without telling whether the
+// dependency works, such as one canceled by its caller. A probe abandoned
+// this way is handed to the next call.
+func (b *Breaker) Abandon() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == HalfOpen {
+		b.state = Open
+	}
+}
+
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := Stats{
+		State:    b.state,
+		Failures: b.failures,
+	}
+	if b.state != Closed {
+		stats.OpenedAt = b.openedAt
+	}
+	return stats
+}
@@ -0,0 +1,122 @@
+package breaker_test
+
+import (
+	"testing"
+	"time"
+	"url_shortener/internal/utils/breaker"
+)
+
+const cooldown = 20 * time.Millisecond
+
+// fail makes an allowed call that fails.
+func fail(t *testing.T, b *breaker.Breaker) {
+	t.Helper()
+	if _, ok := b.Allow(); !ok {
+		t.Fatalf("Allow() rejected a call while %s", b.Stats().State)
+	}
+	b.Record(true)
+}
+
+func wantState(t *testing.T, b *breaker.Breaker, want breaker.State) {
+	t.Helper()
+	if got := b.Stats().State; got != want {
+		t.Fatalf("state = %s, want %s", got, want)
+	}
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := breaker.New(3, cooldown)
+
+	fail(t, b)
+	fail(t, b)
+	// A success resets the count.
+	b.Allow()
+	b.Record(false)
+	if stats := b.Stats(); stats.Failures != 0 || !stats.OpenedAt.IsZero() {
+		t.Fatalf("Stats() = %+v after a success", stats)
+	}
+
+	fail(t, b)
+	fail(t, b)
+	wantState(t, b, breaker.Closed)
+	fail(t, b)
+	wantState(t, b, breaker.Open)
+
+	stats := b.Stats()
+	if stats.Failures != 3 || stats.OpenedAt.IsZero() {
+		t.Fatalf("Stats() = %+v, want 3 failures and OpenedAt set", stats)
+	}
+	retryAfter, ok := b.Allow()
+	if ok {
+		t.Fatal("Allow() let a call through while open")
+	}
+	if retryAfter <= 0 || retryAfter > cooldown {
+		t.Fatalf("retryAfter = %s, want within the cooldown", retryAfter)
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	b := breaker.New(1, cooldown)
+	fail(t, b)
+	time.Sleep(cooldown)
+
+	// A single probe goes through once the cooldown has passed.
+	if _, ok := b.Allow(); !ok {
+		t.Fatal("Allow() rejected the probe after the cooldown")
+	}
+	wantState(t, b, breaker.HalfOpen)
+	if _, ok := b.Allow(); ok {
+		t.Fatal("Allow() let a second call through while half open")
+	}
+
+	// A failed probe opens it again for another cooldown.
+	b.Record(true)
+	wantState(t, b, breaker.Open)
+	if _, ok := b.Allow(); ok {
+		t.Fatal("Allow() let a call through right after a failed probe")
+	}
+	time.Sleep(cooldown)
+
+	// A successful probe closes it.
+	if _, ok := b.Allow(); !ok {
+		t.Fatal("Allow() rejected the probe after the cooldown")
+	}
+	b.Record(false)
+	wantState(t, b, breaker.Closed)
+	if stats := b.Stats(); stats.Failures != 0 || !stats.OpenedAt.IsZero() {
+		t.Fatalf("Stats() = %+v after closing", stats)
+	}
+}
+
+func TestBreakerAbandonHandsProbeOver(t *testing.T) {
+	b := breaker.New(1, cooldown)
+	fail(t, b)
+	time.Sleep(cooldown)
+
+	if _, ok := b.Allow(); !ok {
+		t.Fatal("Allow() rejected the probe after the cooldown")
+	}
+	b.Abandon()
+	wantState(t, b, breaker.Open)
+
+	// The next call gets the probe without waiting another cooldown.
+	if _, ok := b.Allow(); !ok {
+		t.Fatal("Allow() rejected the call after an abandoned probe")
+	}
+	wantState(t, b, breaker.HalfOpen)
+}
+
+func TestBreakerAbandonWhileClosed(t *testing.T) {
+	b := breaker.New(1, cooldown)
+	b.Allow()
+	b.Abandon()
+	wantState(t, b, breaker.Closed)
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := breaker.New(0, cooldown)
+	for range 10 {
+		fail(t, b)
+	}
+	wantState(t, b, breaker.Closed)
+}
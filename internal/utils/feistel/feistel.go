@@ -0,0 +1,75 @@
+package feistel
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+const rounds = 6
+
+// Cipher is a keyed balanced Feistel network over the integers
+// [0, 2^bits). It is a bijection, so distinct inputs never share an output
+// and every output decrypts back to its input.
+type Cipher struct {
+	key      []byte
+	halfBits uint
+	mask     uint64
+}
+
+// New returns a cipher over bits-wide integers; bits is rounded up to an
+// even number between 2 and 62.
+func New(key []byte, bits int) *Cipher {
+	bits = min(max(bits+bits%2, 2), 62) //nolint:mnd // two halves of at most 31 bits
+	half := uint(bits / 2)              //nolint:gosec // bits is positive
+	return &Cipher{
+		key:      key,
+		halfBits: half,
+		mask:     1<<half - 1,
+	}
+}
+
+func (c *Cipher) Encrypt(x uint64) uint64 {
+	l, r := x>>c.halfBits&c.mask, x&c.mask
+	for i := range rounds {
+		l, r = r, l^c.round(i, r)
+	}
+	return l<<c.halfBits | r
+}
+
+func (c *Cipher) Decrypt(x uint64) uint64 {
+	l, r := x>>c.halfBits&c.mask, x&c.mask
+	for i := rounds - 1; i >= 0; i-- {
+		l, r = r^c.round(i, l), l
+	}
+	return l<<c.halfBits | r
+}
+
+// EncryptIn permutes x within [0, n) by cycle walking: outputs that land
+// outside the domain are encrypted again until they fall inside it. n must
+// not exceed 2^bits and x must be below n.
+func (c *Cipher) EncryptIn(x, n uint64) uint64 {
+	x = c.Encrypt(x)
+	for x >= n {
+		x = c.Encrypt(x)
+	}
+	return x
+}
+
+func (c *Cipher) DecryptIn(x, n uint64) uint64 {
+	x = c.Decrypt(x)
+	for x >= n {
+		x = c.Decrypt(x)
+	}
+	return x
+}
+
+func (c *Cipher) round(i int, half uint64) uint64 {
+	var buf [9]byte
+	buf[0] = byte(i)
+	binary.BigEndian.PutUint64(buf[1:], half)
+
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(buf[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil)) & c.mask
+}
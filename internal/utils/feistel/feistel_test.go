@@ -0,0 +1,73 @@
+package feistel_test
+
+import (
+	"testing"
+	"url_shortener/internal/utils/feistel"
+)
+
+func TestCipherIsBijection(t *testing.T) {
+	for _, bits := range []int{2, 8, 12} {
+		cipher := feistel.New([]byte("0123456789abcdef"), bits)
+		n := uint64(1) << bits
+
+		seen := make(map[uint64]struct{}, n)
+		for x := range n {
+			y := cipher.Encrypt(x)
+			if y >= n {
+				t.Fatalf("bits %d: Encrypt(%d) = %d, out of range", bits, x, y)
+			}
+			if _, ok := seen[y]; ok {
+				t.Fatalf("bits %d: Encrypt(%d) = %d, already produced", bits, x, y)
+			}
+			seen[y] = struct{}{}
+			if got := cipher.Decrypt(y); got != x {
+				t.Fatalf("bits %d: Decrypt(Encrypt(%d)) = %d", bits, x, got)
+			}
+		}
+	}
+}
+
+func TestCipherInIsBijection(t *testing.T) {
+	cipher := feistel.New([]byte("0123456789abcdef"), 12)
+	// 62^2 is not a power of two, so EncryptIn has to cycle walk.
+	const n = 62 * 62
+
+	seen := make(map[uint64]struct{}, n)
+	for x := range uint64(n) {
+		y := cipher.EncryptIn(x, n)
+		if y >= n {
+			t.Fatalf("EncryptIn(%d) = %d, out of range", x, y)
+		}
+		if _, ok := seen[y]; ok {
+			t.Fatalf("EncryptIn(%d) = %d, already produced", x, y)
+		}
+		seen[y] = struct{}{}
+		if got := cipher.DecryptIn(y, n); got != x {
+			t.Fatalf("DecryptIn(EncryptIn(%d)) = %d", x, got)
+		}
+	}
+}
+
+func TestCipherRoundTripWide(t *testing.T) {
+	cipher := feistel.New([]byte("0123456789abcdef"), 62)
+	for _, x := range []uint64{0, 1, 42, 1<<31 - 1, 1 << 40, 1<<62 - 1} {
+		if got := cipher.Decrypt(cipher.Encrypt(x)); got != x {
+			t.Fatalf("Decrypt(Encrypt(%d)) = %d", x, got)
+		}
+	}
+}
+
+func TestCipherDependsOnKey(t *testing.T) {
+	a := feistel.New([]byte("0123456789abcdef"), 32)
+	b := feistel.New([]byte("fedcba9876543210"), 32)
+
+	same := 0
+	for x := range uint64(100) {
+		if a.Encrypt(x) == b.Encrypt(x) {
+			same++
+		}
+	}
+	if same > 1 {
+		t.Fatalf("%d of 100 values encrypt the same under different keys", same)
+	}
+}
@@ -0,0 +1,146 @@
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// precision sets the number of registers, 2^precision, and with it the
+// standard error of Count, about 1.04/sqrt(2^precision) or 1.6%.
+const (
+	precision = 12
+	registers = 1 << precision
+)
+
+const (
+	encodingSparse = 1
+	encodingDense  = 2
+)
+
+var ErrInvalidSketch = errors.New("hll: invalid sketch")
+
+// Sketch is a HyperLogLog counter of distinct strings: however many values
+// are added it stays within 2^precision bytes, and a few values take a few
+// bytes when marshaled. Values are hashed with a fixed function, so stored
+// sketches can be merged with ones built by any process. It is not safe for
+// concurrent use.
+type Sketch struct {
+	registers [registers]uint8
+}
+
+func New() *Sketch {
+	return &Sketch{}
+}
+
+func (s *Sketch) Add(value string) {
+	h := hash(value)
+	idx := h >> (64 - precision)
+	// The guard bit caps the rank when the remaining bits are all zero.
+	rank := uint8(bits.LeadingZeros64(h<<precision|1<<(precision-1)) + 1)
+	s.registers[idx] = max(s.registers[idx], rank)
+}
+
+// Merge adds the values counted by other, as if they were added to s.
+func (s *Sketch) Merge(other *Sketch) {
+	for i, rank := range other.registers {
+		s.registers[i] = max(s.registers[i], rank)
+	}
+}
+
+// Count estimates the number of distinct values added, falling back to
+// linear counting while many registers are empty, which is close to exact
+// for small counts.
+func (s *Sketch) Count() int64 {
+	var (
+		sum   float64
+		zeros int
+	)
+	for _, rank := range s.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	m := float64(registers)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(estimate))
+}
+
+// MarshalBinary lists the non-empty registers while that is shorter than
+// writing them all.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	used := 0
+	for _, rank := range s.registers {
+		if rank != 0 {
+			used++
+		}
+	}
+
+	if 1+used*3 >= 1+registers {
+		data := make([]byte, 1, 1+registers)
+		data[0] = encodingDense
+		return append(data, s.registers[:]...), nil
+	}
+
+	data := make([]byte, 1, 1+used*3)
+	data[0] = encodingSparse
+	for i, rank := range s.registers {
+		if rank != 0 {
+			data = binary.BigEndian.AppendUint16(data, uint16(i))
+			data = append(data, rank)
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary replaces s with a marshaled sketch; empty data is an
+// empty sketch.
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	s.registers = [registers]uint8{}
+	if len(data) == 0 {
+		return nil
+	}
+
+	switch data[0] {
+	case encodingDense:
+		if len(data) != 1+registers {
+			return ErrInvalidSketch
+		}
+		copy(s.registers[:], data[1:])
+	case encodingSparse:
+		if (len(data)-1)%3 != 0 {
+			return ErrInvalidSketch
+		}
+		for entry := data[1:]; len(entry) > 0; entry = entry[3:] {
+			idx := binary.BigEndian.Uint16(entry)
+			if idx >= registers {
+				return ErrInvalidSketch
+			}
+			s.registers[idx] = entry[2]
+		}
+	default:
+		return ErrInvalidSketch
+	}
+	return nil
+}
+
+// hash is FNV-1a finished with the SplitMix64 mixer, as FNV alone leaves
+// the high bits, which pick the register, poorly spread.
+func hash(value string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(value)) //nolint:errcheck // never fails
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,94 @@
+package hll_test
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"url_shortener/internal/utils/hll"
+)
+
+func TestSketchCount(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 100, 1_000, 10_000, 100_000, 1_000_000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			sketch := hll.New()
+			for i := range n {
+				value := "visitor-" + strconv.Itoa(i)
+				sketch.Add(value)
+				// Repeats don't count.
+				sketch.Add(value)
+			}
+
+			got := sketch.Count()
+			if n <= 100 && got != int64(n) {
+				t.Fatalf("Count() = %d, want exactly %d", got, n)
+			}
+			// Four standard errors.
+			if diff := math.Abs(float64(got-int64(n))) / float64(max(n, 1)); diff > 0.065 {
+				t.Fatalf("Count() = %d, want about %d (off by %.1f%%)", got, n, diff*100)
+			}
+		})
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	a, b, union := hll.New(), hll.New(), hll.New()
+	for i := range 20_000 {
+		value := strconv.Itoa(i)
+		if i < 15_000 {
+			a.Add(value)
+		}
+		if i >= 5_000 {
+			b.Add(value)
+		}
+		union.Add(value)
+	}
+
+	a.Merge(b)
+	if got, want := a.Count(), union.Count(); got != want {
+		t.Fatalf("merged Count() = %d, want %d like the union", got, want)
+	}
+}
+
+func TestSketchMarshalRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 3, 1_000, 100_000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			sketch := hll.New()
+			for i := range n {
+				sketch.Add(strconv.Itoa(i))
+			}
+
+			data, err := sketch.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n == 3 && len(data) > 16 {
+				t.Fatalf("3 values marshal to %d bytes, want a sparse sketch", len(data))
+			}
+			if len(data) > 1+1<<12 {
+				t.Fatalf("sketch marshals to %d bytes, more than the dense form", len(data))
+			}
+
+			decoded := hll.New()
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := decoded.Count(), sketch.Count(); got != want {
+				t.Fatalf("decoded Count() = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestSketchUnmarshalInvalid(t *testing.T) {
+	for _, data := range [][]byte{
+		{9},
+		{1, 0, 1},
+		{1, 0xff, 0xff, 1},
+		{2, 0, 0},
+	} {
+		if err := hll.New().UnmarshalBinary(data); !errors.Is(err, hll.ErrInvalidSketch) {
+			t.Fatalf("UnmarshalBinary(%v) = %v, want ErrInvalidSketch", data, err)
+		}
+	}
+}
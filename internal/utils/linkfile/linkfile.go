@@ -0,0 +1,231 @@
+package linkfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Formats of link files.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// columns are the CSV columns written, and the names JSONL fields are read
+// from. Only short_code and original_url are required on read.
+var (
+	columns         = []string{"short_code", "original_url", "expires_at", "owner_id", "created_at"}
+	requiredColumns = []string{"short_code", "original_url"}
+)
+
+// Record is one link of a file.
+type Record struct {
+	ShortCode   string     `json:"short_code"`
+	OriginalURL string     `json:"original_url"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	OwnerID     string     `json:"owner_id,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// RowError is a record that could not be read. Reading can go on with the
+// next record.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+type Writer struct {
+	csv  *csv.Writer
+	json *json.Encoder
+	buf  *bufio.Writer
+}
+
+// NewWriter writes the CSV header right away, so an empty file still has
+// one.
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &Writer{csv: cw}, nil
+	case FormatJSONL:
+		buf := bufio.NewWriter(w)
+		return &Writer{json: json.NewEncoder(buf), buf: buf}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+func (w *Writer) Write(record Record) error {
+	if w.json != nil {
+		return w.json.Encode(record)
+	}
+	return w.csv.Write([]string{
+		record.ShortCode,
+		record.OriginalURL,
+		formatTime(record.ExpiresAt),
+		record.OwnerID,
+		formatTime(record.CreatedAt),
+	})
+}
+
+func (w *Writer) Flush() error {
+	if w.buf != nil {
+		return w.buf.Flush()
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+type Reader struct {
+	csv    *csv.Reader
+	index  map[string]int
+	lines  *bufio.Reader
+	lineNo int
+}
+
+func NewReader(r io.Reader, format string) (*Reader, error) {
+	switch format {
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		cr.ReuseRecord = true
+		return &Reader{csv: cr}, nil
+	case FormatJSONL:
+		return &Reader{lines: bufio.NewReader(r)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// Read returns the next record and the line it starts on, io.EOF after the
+// last one. A *RowError reports a bad record, any other error means the
+// rest of the file can't be read.
+func (r *Reader) Read() (Record, int, error) {
+	if r.lines != nil {
+		return r.readJSONL()
+	}
+	return r.readCSV()
+}
+
+func (r *Reader) readCSV() (Record, int, error) {
+	if r.index == nil {
+		if err := r.readHeader(); err != nil {
+			return Record{}, 0, err
+		}
+	}
+
+	row, err := r.csv.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			return Record{}, parseErr.StartLine, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return Record{}, 0, err
+	}
+	line, _ := r.csv.FieldPos(0)
+
+	field := func(name string) string {
+		if i, ok := r.index[name]; ok {
+			return row[i]
+		}
+		return ""
+	}
+	record := Record{
+		ShortCode:   field("short_code"),
+		OriginalURL: field("original_url"),
+		OwnerID:     field("owner_id"),
+	}
+	if record.ExpiresAt, err = parseTime(field("expires_at")); err != nil {
+		return Record{}, line, &RowError{Line: line, Err: fmt.Errorf("expires_at: %w", err)}
+	}
+	if record.CreatedAt, err = parseTime(field("created_at")); err != nil {
+		return Record{}, line, &RowError{Line: line, Err: fmt.Errorf("created_at: %w", err)}
+	}
+
+	return record, line, nil
+}
+
+func (r *Reader) readHeader() error {
+	header, err := r.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("header: %w", err)
+	}
+
+	r.index = make(map[string]int, len(header))
+	for i, name := range header {
+		r.index[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := r.index[name]; !ok {
+			return fmt.Errorf("header: missing column %q", name)
+		}
+	}
+	return nil
+}
+
+func (r *Reader) readJSONL() (Record, int, error) {
+	for {
+		line, err := r.lines.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return Record{}, 0, err
+		}
+		r.lineNo++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return Record{}, r.lineNo, &RowError{Line: r.lineNo, Err: err}
+		}
+		return record, r.lineNo, nil
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil //nolint:nilnil // empty column has no time
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
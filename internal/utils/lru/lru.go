@@ -0,0 +1,70 @@
+package lru
+
+import "container/list"
+
+// Cache is a fixed capacity map that evicts the least recently used entry
+// when full. It is not safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	capacity int
+	ll       *list.List
+	items    map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func New[K comparable, V any](capacity int) *Cache[K, V] {
+	return &Cache[K, V]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[K]*list.Element, capacity),
+	}
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*entry[K, V]).value, true //nolint:errcheck,forcetypeassert // only entries are stored
+}
+
+// Add inserts or replaces the value of key and reports whether another
+// entry was evicted to make room for it.
+func (c *Cache[K, V]) Add(key K, value V) bool {
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		elem.Value.(*entry[K, V]).value = value //nolint:errcheck,forcetypeassert // only entries are stored
+		return false
+	}
+
+	c.items[key] = c.ll.PushFront(&entry[K, V]{key: key, value: value})
+	if c.ll.Len() <= c.capacity {
+		return false
+	}
+
+	oldest := c.ll.Back()
+	c.ll.Remove(oldest)
+	delete(c.items, oldest.Value.(*entry[K, V]).key) //nolint:errcheck,forcetypeassert // only entries are stored
+	return true
+}
+
+func (c *Cache[K, V]) Remove(key K) {
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+func (c *Cache[K, V]) Purge() {
+	c.ll.Init()
+	clear(c.items)
+}
+
+func (c *Cache[K, V]) Len() int {
+	return c.ll.Len()
+}
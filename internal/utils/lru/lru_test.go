@@ -0,0 +1,70 @@
+package lru_test
+
+import (
+	"testing"
+	"url_shortener/internal/utils/lru"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := lru.New[string, int](2)
+
+	if cache.Add("a", 1) || cache.Add("b", 2) {
+		t.Fatal("Add evicted before the cache was full")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal(`Get("a") missed`)
+	}
+	if !cache.Add("c", 3) {
+		t.Fatal("Add past capacity didn't evict")
+	}
+
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal(`"b" was used least recently but survived`)
+	}
+	for key, want := range map[string]int{"a": 1, "c": 3} {
+		if got, ok := cache.Get(key); !ok || got != want {
+			t.Fatalf("Get(%q) = %d, %v, want %d", key, got, ok, want)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", cache.Len())
+	}
+}
+
+func TestCacheAddReplacesAndRefreshes(t *testing.T) {
+	cache := lru.New[string, int](2)
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+
+	if cache.Add("a", 10) {
+		t.Fatal("replacing a key evicted another")
+	}
+	cache.Add("c", 3)
+
+	if got, ok := cache.Get("a"); !ok || got != 10 {
+		t.Fatalf(`Get("a") = %d, %v, want 10`, got, ok)
+	}
+	if _, ok := cache.Get("b"); ok {
+		t.Fatal(`"b" should have been evicted after "a" was replaced`)
+	}
+}
+
+func TestCacheRemoveAndPurge(t *testing.T) {
+	cache := lru.New[string, int](3)
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+
+	cache.Remove("a")
+	cache.Remove("missing")
+	if _, ok := cache.Get("a"); ok || cache.Len() != 1 {
+		t.Fatalf(`after Remove("a"): Len() = %d, "a" present %v`, cache.Len(), ok)
+	}
+
+	cache.Purge()
+	if _, ok := cache.Get("b"); ok || cache.Len() != 0 {
+		t.Fatalf("after Purge: Len() = %d", cache.Len())
+	}
+	if cache.Add("c", 3) {
+		t.Fatal("Add evicted from a purged cache")
+	}
+}
@@ -0,0 +1,111 @@
+package shortcode
+
+import (
+	"errors"
+	"math/bits"
+	"strings"
+	"url_shortener/internal/utils/base62"
+	"url_shortener/internal/utils/feistel"
+)
+
+const (
+	base = 62
+
+	// MaxLength is the longest code whose space, 62^10, still fits the
+	// 62-bit cipher.
+	MaxLength = 10
+)
+
+var ErrOutOfRange = errors.New("counter value out of short code range")
+
+// Codec turns counter values into short codes and back. Without a key the
+// code is the plain base62 value. With a key each code length is its own
+// space of 62^length codes, permuted by a Feistel cipher, so codes look
+// random yet stay unique and decodable: a value is encoded with the
+// shortest length, starting at minLength, whose space contains it.
+type Codec struct {
+	minLength int
+	ciphers   map[int]*feistel.Cipher
+}
+
+func New(key string, minLength int) *Codec {
+	c := &Codec{minLength: minLength}
+	if key == "" {
+		return c
+	}
+
+	c.ciphers = make(map[int]*feistel.Cipher, MaxLength-minLength+1)
+	for length := minLength; length <= MaxLength; length++ {
+		c.ciphers[length] = feistel.New([]byte(key), bits.Len64(space(length)-1))
+	}
+	return c
+}
+
+func (c *Codec) Encode(num int) (string, error) {
+	if c.ciphers == nil {
+		return base62.Encode(num), nil
+	}
+	if num < 0 {
+		return "", ErrOutOfRange
+	}
+
+	value := uint64(num)
+	length := c.minLength
+	for value >= space(length) {
+		length++
+		if length > MaxLength {
+			return "", ErrOutOfRange
+		}
+	}
+
+	permuted := c.ciphers[length].EncryptIn(value, space(length))
+	code := base62.Encode(int(permuted)) //nolint:gosec // below 62^10
+	return strings.Repeat("0", length-len(code)) + code, nil
+}
+
+// Decode returns the counter value code was generated from and false when
+// code could not have been produced by Encode.
+func (c *Codec) Decode(code string) (int, bool) {
+	if !valid(code) {
+		return 0, false
+	}
+	if c.ciphers == nil {
+		if len(code) > 1 && code[0] == '0' {
+			return 0, false
+		}
+		return base62.Decode(code), true
+	}
+
+	length := len(code)
+	cipher, ok := c.ciphers[length]
+	if !ok {
+		return 0, false
+	}
+
+	value := cipher.DecryptIn(uint64(base62.Decode(code)), space(length)) //nolint:gosec // below 62^10
+	if length > c.minLength && value < space(length-1) {
+		return 0, false
+	}
+	return int(value), true //nolint:gosec // below 62^10
+}
+
+func space(length int) uint64 {
+	n := uint64(1)
+	for range length {
+		n *= base
+	}
+	return n
+}
+
+func valid(code string) bool {
+	if code == "" || len(code) > MaxLength {
+		return false
+	}
+	for i := range len(code) {
+		ch := code[i]
+		if (ch < '0' || ch > '9') && (ch < 'A' || ch > 'Z') && (ch < 'a' || ch > 'z') {
+			return false
+		}
+	}
+	return true
+}
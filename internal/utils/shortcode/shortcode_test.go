@@ -0,0 +1,123 @@
+package shortcode_test
+
+import (
+	"errors"
+	"testing"
+	"url_shortener/internal/utils/shortcode"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, key := range []string{"", "0123456789abcdef"} {
+		codec := shortcode.New(key, 2)
+		for _, num := range []int{0, 1, 61, 62, 3843, 3844, 238327, 238328, 1_000_000, 839299365868340223} {
+			code, err := codec.Encode(num)
+			if err != nil {
+				t.Fatalf("key %q: Encode(%d): %v", key, num, err)
+			}
+			got, ok := codec.Decode(code)
+			if !ok || got != num {
+				t.Fatalf("key %q: Decode(%q) = %d, %v, want %d", key, code, got, ok, num)
+			}
+		}
+	}
+}
+
+func TestCodecUsesShortestLength(t *testing.T) {
+	codec := shortcode.New("0123456789abcdef", 3)
+
+	tests := []struct {
+		num    int
+		length int
+	}{
+		{0, 3},
+		{62*62*62 - 1, 3},
+		{62 * 62 * 62, 4},
+		{62*62*62*62 - 1, 4},
+		{62 * 62 * 62 * 62, 5},
+	}
+	for _, tt := range tests {
+		code, err := codec.Encode(tt.num)
+		if err != nil {
+			t.Fatalf("Encode(%d): %v", tt.num, err)
+		}
+		if len(code) != tt.length {
+			t.Fatalf("Encode(%d) = %q, want length %d", tt.num, code, tt.length)
+		}
+	}
+}
+
+// Every code of a length decodes to a distinct value, and only codes of the
+// minimal length of their value are accepted, so no two codes share one.
+func TestCodecDecodesOnlyMinimalCodes(t *testing.T) {
+	const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	codec := shortcode.New("0123456789abcdef", 1)
+
+	decoded := make(map[int]string)
+	for _, length := range []int{1, 2} {
+		rejected := 0
+		codes := []string{""}
+		for range length {
+			var next []string
+			for _, prefix := range codes {
+				for i := range len(base62) {
+					next = append(next, prefix+base62[i:i+1])
+				}
+			}
+			codes = next
+		}
+
+		for _, code := range codes {
+			num, ok := codec.Decode(code)
+			if !ok {
+				rejected++
+				continue
+			}
+			if other, ok := decoded[num]; ok {
+				t.Fatalf("%q and %q both decode to %d", other, code, num)
+			}
+			decoded[num] = code
+			if encoded, err := codec.Encode(num); err != nil || encoded != code {
+				t.Fatalf("Encode(Decode(%q)) = %q, %v", code, encoded, err)
+			}
+		}
+
+		// Values below 62^(length-1) have a shorter code.
+		want := 0
+		if length > 1 {
+			want = 62
+		}
+		if rejected != want {
+			t.Fatalf("length %d: %d codes rejected, want %d", length, rejected, want)
+		}
+	}
+}
+
+func TestCodecWithoutKeyRejectsLeadingZero(t *testing.T) {
+	codec := shortcode.New("", 1)
+	if _, ok := codec.Decode("01"); ok {
+		t.Fatal(`Decode("01") accepted a code Encode never produces`)
+	}
+	if num, ok := codec.Decode("0"); !ok || num != 0 {
+		t.Fatalf(`Decode("0") = %d, %v, want 0`, num, ok)
+	}
+}
+
+func TestCodecRejectsInvalidCodes(t *testing.T) {
+	codec := shortcode.New("0123456789abcdef", 6)
+	for _, code := range []string{"", "abc", "abc-de", "abcdefghijk", "ab_cd1"} {
+		if _, ok := codec.Decode(code); ok {
+			t.Fatalf("Decode(%q) accepted an invalid code", code)
+		}
+	}
+}
+
+func TestCodecOutOfRange(t *testing.T) {
+	codec := shortcode.New("0123456789abcdef", 6)
+	if _, err := codec.Encode(-1); !errors.Is(err, shortcode.ErrOutOfRange) {
+		t.Fatalf("Encode(-1) = %v, want ErrOutOfRange", err)
+	}
+	// 62^10 is the first value past MaxLength.
+	if _, err := codec.Encode(839299365868340224); !errors.Is(err, shortcode.ErrOutOfRange) {
+		t.Fatalf("Encode(62^10) = %v, want ErrOutOfRange", err)
+	}
+}
@@ -0,0 +1,208 @@
+package ssrf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrBlockedAddress = errors.New("address is not public")
+	ErrNoAddress      = errors.New("host has no addresses")
+)
+
+// blockedPrefixes are special purpose ranges not covered by the netip
+// predicates used in CheckAddr.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT, Alibaba Cloud metadata
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, broadcast
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+var (
+	nat64Prefix  = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFour    = netip.MustParsePrefix("2002::/16")
+	mappedPrefix = netip.MustParsePrefix("::ffff:0:0/96")
+)
+
+// Resolver looks up the addresses of a host; *net.Resolver implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Validator rejects destinations that resolve to loopback, private,
+// link-local (including the 169.254.169.254 cloud metadata endpoint) and
+// other non-public addresses, unless the address or host is in the allow
+// list.
+type Validator struct {
+	resolver      Resolver
+	allowHosts    map[string]struct{}
+	allowPrefixes []netip.Prefix
+}
+
+// New takes allow entries that are IP addresses, CIDR prefixes or host
+// names.
+func New(resolver Resolver, allow []string) (*Validator, error) {
+	v := &Validator{
+		resolver:   resolver,
+		allowHosts: make(map[string]struct{}),
+	}
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("allow entry %q: %w", entry, err)
+			}
+			v.allowPrefixes = append(v.allowPrefixes, prefix.Masked())
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				v.allowPrefixes = append(v.allowPrefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+				continue
+			}
+			v.allowHosts[strings.TrimSuffix(entry, ".")] = struct{}{}
+		}
+	}
+	return v, nil
+}
+
+func MustNew(resolver Resolver, allow []string) *Validator {
+	v, err := New(resolver, allow)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Validate resolves host and fails if any of its addresses is blocked. It
+// only tells the state at call time: a fetcher must also dial through
+// Control, or use NewHTTPClient, since DNS can answer differently on
+// connect.
+func (v *Validator) Validate(ctx context.Context, host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if v.allowedHost(host) {
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return v.CheckAddr(addr)
+	}
+
+	addrs, err := v.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("resolve %s: %w", host, ErrNoAddress)
+	}
+	for _, addr := range addrs {
+		if err := v.CheckAddr(addr); err != nil {
+			return fmt.Errorf("resolve %s: %w", host, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckAddr fails for blocked addresses. IPv4 addresses embedded in IPv6
+// (mapped, NAT64 and 6to4) are checked as the IPv4 address they carry.
+func (v *Validator) CheckAddr(addr netip.Addr) error {
+	addr = embeddedIPv4(addr.Unmap().WithZone(""))
+
+	for _, prefix := range v.allowPrefixes {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+		}
+	}
+
+	return nil
+}
+
+// Control is a net.Dialer Control function that checks the address
+// actually being connected to, which defeats DNS rebinding between
+// Validate and the connection. It only sees the address, so a dialer must
+// let hosts in the allow list through itself, as NewHTTPClient does.
+func (v *Validator) Control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", address, err)
+	}
+	return v.CheckAddr(addrPort.Addr())
+}
+
+// NewHTTPClient returns a client for server-side fetches of user supplied
+// urls: every connection, including those of redirects, is checked by
+// Control unless its host is in the allow list, and proxies from the
+// environment are ignored since they would hide the real destination.
+func (v *Validator) NewHTTPClient(timeout time.Duration) *http.Client {
+	allowed := &net.Dialer{Timeout: timeout}
+	checked := &net.Dialer{
+		Timeout: timeout,
+		Control: v.Control,
+	}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			if host, _, err := net.SplitHostPort(address); err == nil && v.allowedHost(host) {
+				return allowed.DialContext(ctx, network, address)
+			}
+			return checked.DialContext(ctx, network, address)
+		},
+		TLSHandshakeTimeout: timeout,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+func (v *Validator) allowedHost(host string) bool {
+	_, ok := v.allowHosts[strings.TrimSuffix(strings.ToLower(host), ".")]
+	return ok
+}
+
+func embeddedIPv4(addr netip.Addr) netip.Addr {
+	if !addr.Is6() {
+		return addr
+	}
+	b := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr), mappedPrefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[12:16]))
+	case sixToFour.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[2:6]))
+	default:
+		return addr
+	}
+}
@@ -0,0 +1,163 @@
+package ssrf_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+	"url_shortener/internal/utils/ssrf"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	addrs, ok := f[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	result := make([]netip.Addr, len(addrs))
+	for i, addr := range addrs {
+		result[i] = netip.MustParseAddr(addr)
+	}
+	return result, nil
+}
+
+func TestCheckAddr(t *testing.T) {
+	validator := ssrf.MustNew(fakeResolver{}, nil)
+
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"::", true},
+		{"fc00::1", true},
+		{"fe80::1%eth0", true},
+		{"224.0.0.1", true},
+		{"ff02::1", true},
+		{"255.255.255.255", true},
+		{"2001:db8::1", true},
+		// cloud metadata endpoints
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"fd00:ec2::254", true},
+		// IPv4 carried in IPv6
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"64:ff9b::808:808", false},
+		{"2002:7f00:1::", true},
+		{"2002:a9fe:a9fe::1", true},
+		{"2002:808:808::", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := validator.CheckAddr(netip.MustParseAddr(tt.addr))
+			if blocked := errors.Is(err, ssrf.ErrBlockedAddress); blocked != tt.blocked {
+				t.Fatalf("CheckAddr(%s) = %v, want blocked %v", tt.addr, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	resolver := fakeResolver{
+		"public.example":   {"93.184.216.34", "2606:2800:220:1::1"},
+		"private.example":  {"93.184.216.34", "10.0.0.1"},
+		"metadata.example": {"169.254.169.254"},
+		"mapped.example":   {"::ffff:192.168.0.1"},
+		"internal.example": {"10.0.0.2"},
+		"office.example":   {"192.168.10.5"},
+		"empty.example":    {},
+	}
+	validator := ssrf.MustNew(resolver, []string{"internal.example", "192.168.10.0/24", "127.0.0.2"})
+
+	tests := []struct {
+		name string
+		host string
+		want error
+	}{
+		{"public", "public.example", nil},
+		{"upper case and trailing dot", "PUBLIC.example.", nil},
+		{"any private address", "private.example", ssrf.ErrBlockedAddress},
+		{"metadata", "metadata.example", ssrf.ErrBlockedAddress},
+		{"mapped", "mapped.example", ssrf.ErrBlockedAddress},
+		{"allowed host", "internal.example", nil},
+		{"allowed prefix", "office.example", nil},
+		{"no addresses", "empty.example", ssrf.ErrNoAddress},
+		{"ip literal", "127.0.0.1", ssrf.ErrBlockedAddress},
+		{"bracketed ip literal", "[::1]", ssrf.ErrBlockedAddress},
+		{"allowed ip literal", "127.0.0.2", nil},
+		{"allowed mapped ip literal", "::ffff:127.0.0.2", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(context.Background(), tt.host)
+			if tt.want == nil && err != nil {
+				t.Fatalf("Validate(%s) = %v, want nil", tt.host, err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Validate(%s) = %v, want %v", tt.host, err, tt.want)
+			}
+		})
+	}
+
+	if err := validator.Validate(context.Background(), "unknown.example"); err == nil {
+		t.Fatal("Validate(unknown.example) = nil, want the resolver error")
+	}
+}
+
+func TestNewRejectsBadPrefix(t *testing.T) {
+	if _, err := ssrf.New(fakeResolver{}, []string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("New accepted an invalid prefix")
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	tests := []struct {
+		name    string
+		allow   []string
+		host    string
+		blocked bool
+	}{
+		{"loopback", nil, "localhost", true},
+		{"loopback ip", nil, "127.0.0.1", true},
+		{"allowed host", []string{"localhost"}, "localhost", false},
+		{"allowed host with trailing dot", []string{"LOCALHOST."}, "localhost", false},
+		{"other host allowed", []string{"internal.example"}, "localhost", true},
+		{"allowed address", []string{"127.0.0.1"}, "127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := ssrf.MustNew(fakeResolver{}, tt.allow).NewHTTPClient(time.Second)
+
+			resp, err := client.Get("http://" + net.JoinHostPort(tt.host, port))
+			if err == nil {
+				resp.Body.Close()
+			}
+			if blocked := errors.Is(err, ssrf.ErrBlockedAddress); blocked != tt.blocked {
+				t.Fatalf("Get(%s) = %v, want blocked %v", tt.host, err, tt.blocked)
+			}
+			if !tt.blocked && err != nil {
+				t.Fatalf("Get(%s) = %v", tt.host, err)
+			}
+		})
+	}
+}
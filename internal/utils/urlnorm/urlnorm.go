@@ -0,0 +1,169 @@
+package urlnorm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrInvalid          = errors.New("invalid url")
+	ErrSchemeNotAllowed = errors.New("scheme not allowed")
+)
+
+// hostProfile is idna.Lookup without the STD3 rules, which reject the
+// underscores some real hosts have.
+var hostProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false))
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
+// trackingParams are dropped when tracking stripping is enabled; names
+// ending in "_" match as prefixes.
+var trackingParams = []string{
+	"utm_", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid",
+	"yclid", "mc_cid", "mc_eid", "igshid", "_hsenc", "_hsmi",
+}
+
+// Normalizer rewrites urls into a canonical form so that equivalent
+// spellings of the same destination compare equal.
+type Normalizer struct {
+	schemes       []string
+	stripTracking bool
+}
+
+func New(schemes []string, stripTracking bool) *Normalizer {
+	return &Normalizer{
+		schemes:       schemes,
+		stripTracking: stripTracking,
+	}
+}
+
+// Normalize lowercases the scheme and host, converts international domain
+// names to punycode, drops default ports, resolves dot segments, sorts the
+// query and, when enabled, strips tracking parameters. Only absolute urls
+// with a host and an allowed scheme are accepted.
+func (n *Normalizer) Normalize(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !slices.Contains(n.schemes, u.Scheme) {
+		return "", fmt.Errorf("%w: %q", ErrSchemeNotAllowed, u.Scheme)
+	}
+	if u.Opaque != "" || u.Host == "" {
+		return "", fmt.Errorf("%w: missing host", ErrInvalid)
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	u.Host = host
+	if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	}
+	if port != "" {
+		u.Host += ":" + port
+	}
+
+	path := removeDotSegments(u.EscapedPath())
+	if path == "" {
+		path = "/"
+	}
+	if u.Path, err = url.PathUnescape(path); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	u.RawPath = path
+
+	query := u.Query()
+	if n.stripTracking {
+		for name := range query {
+			if isTrackingParam(name) {
+				query.Del(name)
+			}
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", errors.New("missing host")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	if isASCII(host) {
+		return host, nil
+	}
+	return hostProfile.ToASCII(host)
+}
+
+func isASCII(s string) bool {
+	for i := range len(s) {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// removeDotSegments implements RFC 3986 section 5.2.4.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, segment)
+		}
+	}
+
+	return strings.Join(out, "/")
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, param := range trackingParams {
+		if name == param || (strings.HasSuffix(param, "_") && strings.HasPrefix(name, param)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package urlnorm_test
+
+import (
+	"errors"
+	"testing"
+	"url_shortener/internal/utils/urlnorm"
+)
+
+func TestNormalize(t *testing.T) {
+	normalizer := urlnorm.New([]string{"http", "https"}, false)
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"HTTP://Example.com:80/a/../b?", "http://example.com/b"},
+		{"  http://example.com  ", "http://example.com/"},
+		// default ports
+		{"https://example.com:443", "https://example.com/"},
+		{"http://example.com:/", "http://example.com/"},
+		{"https://example.com:8443/x", "https://example.com:8443/x"},
+		{"http://example.com:443/", "http://example.com:443/"},
+		// dot segments
+		{"http://example.com/a/./b/../c", "http://example.com/a/c"},
+		{"http://example.com/a/b/..", "http://example.com/a/"},
+		{"http://example.com/a/b/.", "http://example.com/a/b/"},
+		{"http://example.com/../../a", "http://example.com/a"},
+		{"http://example.com/a.b/c..d", "http://example.com/a.b/c..d"},
+		// hosts
+		{"http://EXAMPLE.com./", "http://example.com/"},
+		{"http://bücher.example/", "http://xn--bcher-kva.example/"},
+		{"http://Bücher.example/", "http://xn--bcher-kva.example/"},
+		{"http://ａｂｃ.example/", "http://abc.example/"},
+		{"http://my_host.example/", "http://my_host.example/"},
+		{"http://[2001:DB8::1]:80/", "http://[2001:db8::1]/"},
+		// paths, queries and fragments
+		{"http://example.com/a%2Fb", "http://example.com/a%2Fb"},
+		{"http://example.com/?b=2&a=1", "http://example.com/?a=1&b=2"},
+		{"http://example.com/?utm_source=x&id=1", "http://example.com/?id=1&utm_source=x"},
+		{"http://example.com/a#frag", "http://example.com/a#frag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := normalizer.Normalize(tt.raw)
+			if err != nil {
+				t.Fatalf("Normalize(%q) = %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+			// Normalizing is idempotent, so the backfill can run again.
+			if again, err := normalizer.Normalize(got); err != nil || again != got {
+				t.Fatalf("Normalize(%q) = %q, %v, want it unchanged", got, again, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeStripsTracking(t *testing.T) {
+	normalizer := urlnorm.New([]string{"http", "https"}, true)
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"http://example.com/?utm_source=x&id=1&fbclid=y", "http://example.com/?id=1"},
+		{"http://example.com/?UTM_Medium=z&gclid=1", "http://example.com/"},
+		{"http://example.com/?utm=1&utmost=2", "http://example.com/?utm=1&utmost=2"},
+	}
+	for _, tt := range tests {
+		got, err := normalizer.Normalize(tt.raw)
+		if err != nil || got != tt.want {
+			t.Fatalf("Normalize(%q) = %q, %v, want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	normalizer := urlnorm.New([]string{"http", "https"}, false)
+
+	tests := []struct {
+		raw  string
+		want error
+	}{
+		{"ftp://example.com/", urlnorm.ErrSchemeNotAllowed},
+		{"javascript:alert(1)", urlnorm.ErrSchemeNotAllowed},
+		{"mailto:someone@example.com", urlnorm.ErrSchemeNotAllowed},
+		{"example.com/a", urlnorm.ErrSchemeNotAllowed},
+		{"http:///path", urlnorm.ErrInvalid},
+		{"http:example.com", urlnorm.ErrInvalid},
+		{"http://.", urlnorm.ErrInvalid},
+		{"http://exa mple.com/", urlnorm.ErrInvalid},
+		{"http://example.com/%zz", urlnorm.ErrInvalid},
+		{"http://١.example/", urlnorm.ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if _, err := normalizer.Normalize(tt.raw); !errors.Is(err, tt.want) {
+				t.Fatalf("Normalize(%q) = %v, want %v", tt.raw, err, tt.want)
+			}
+		})
+	}
+}
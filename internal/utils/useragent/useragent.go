@@ -0,0 +1,58 @@
+package useragent
+
+import "strings"
+
+const Other = "Other"
+
+type rule struct {
+	token string
+	name  string
+}
+
+// Order matters: Chromium based browsers also send "Chrome/" and "Safari/",
+// and Android sends "Linux".
+var (
+	browserRules = []rule{
+		{"bot", "Bot"},
+		{"spider", "Bot"},
+		{"crawl", "Bot"},
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"samsungbrowser/", "Samsung Internet"},
+		{"yabrowser/", "Yandex"},
+		{"firefox/", "Firefox"},
+		{"fxios/", "Firefox"},
+		{"crios/", "Chrome"},
+		{"chrome/", "Chrome"},
+		{"safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+
+	osRules = []rule{
+		{"windows", "Windows"},
+		{"android", "Android"},
+		{"iphone", "iOS"},
+		{"ipad", "iOS"},
+		{"ipod", "iOS"},
+		{"cros", "ChromeOS"},
+		{"mac os x", "macOS"},
+		{"macintosh", "macOS"},
+		{"linux", "Linux"},
+	}
+)
+
+// Parse returns coarse browser and operating system families of a
+// User-Agent header, or Other when they are not recognized.
+func Parse(ua string) (string, string) {
+	ua = strings.ToLower(ua)
+	return match(ua, browserRules), match(ua, osRules)
+}
+
+func match(ua string, rules []rule) string {
+	for _, r := range rules {
+		if strings.Contains(ua, r.token) {
+			return r.name
+		}
+	}
+	return Other
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// BlocklistReloader picks up edits of the blocklist file without a
+// restart. A file that fails to parse is reported and the rules loaded
+// before stay in effect.
+type BlocklistReloader struct {
+	interval            time.Duration
+	logger              *slog.Logger
+	blocklistRepository BlocklistRepository
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewBlocklistReloader(
+	interval time.Duration,
+	logger *slog.Logger,
+	blocklistRepository BlocklistRepository,
+) *BlocklistReloader {
+	return &BlocklistReloader{
+		interval:            interval,
+		logger:              logger,
+		blocklistRepository: blocklistRepository,
+	}
+}
+
+func (b *BlocklistReloader) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+
+	go b.run(ctx)
+}
+
+func (b *BlocklistReloader) Stop() {
+	if b.cancel == nil {
+		return
+	}
+	b.cancel()
+	<-b.done
+}
+
+func (b *BlocklistReloader) run(ctx context.Context) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := b.blocklistRepository.Reload()
+			if err != nil {
+				b.logger.ErrorContext(ctx, "failed to reload blocklist",
+					slog.Any("error", err),
+				)
+				continue
+			}
+			if reloaded {
+				b.logger.InfoContext(ctx, "reloaded blocklist",
+					slog.Int("rules", b.blocklistRepository.Len()),
+				)
+			}
+		}
+	}
+}
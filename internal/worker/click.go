@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url_shortener/internal/model"
+)
+
+const (
+	OverflowDrop  = "drop"
+	OverflowBlock = "block"
+
+	clickFlushTimeout = 10 * time.Second
+)
+
+type ClickPoolStats struct {
+	Enqueued int64 `json:"enqueued"`
+	Dropped  int64 `json:"dropped"`
+	Blocked  int64 `json:"blocked"`
+	Written  int64 `json:"written"`
+	Failed   int64 `json:"failed"`
+}
+
+// ClickPool buffers click events in a bounded queue and writes them to the
+// click repository in batches from a fixed number of workers. When the queue
+// is full Enqueue either drops the event or blocks, depending on overflow.
+type ClickPool struct {
+	workers         int
+	batchSize       int
+	flushInterval   time.Duration
+	overflow        string
+	logger          *slog.Logger
+	clickRepository ClickRepository
+
+	queue  chan model.Click
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	enqueued atomic.Int64
+	dropped  atomic.Int64
+	blocked  atomic.Int64
+	written  atomic.Int64
+	failed   atomic.Int64
+}
+
+func NewClickPool(
+	queueSize int,
+	workers int,
+	batchSize int,
+	flushInterval time.Duration,
+	overflow string,
+	logger *slog.Logger,
+	clickRepository ClickRepository,
+) *ClickPool {
+	return &ClickPool{
+		workers:         workers,
+		batchSize:       batchSize,
+		flushInterval:   flushInterval,
+		overflow:        overflow,
+		logger:          logger,
+		clickRepository: clickRepository,
+		queue:           make(chan model.Click, queueSize),
+	}
+}
+
+func (p *ClickPool) Start() {
+	for range p.workers {
+		p.wg.Add(1)
+		go p.run()
+	}
+}
+
+// Stop closes the queue and waits until the workers have written every
+// event still buffered in it.
+func (p *ClickPool) Stop() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+
+	p.logger.Info("click pool drained", slog.Any("stats", p.Stats()))
+}
+
+// Enqueue reports whether the click was accepted.
+func (p *ClickPool) Enqueue(ctx context.Context, click model.Click) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.dropped.Add(1)
+		return false
+	}
+
+	select {
+	case p.queue <- click:
+		p.enqueued.Add(1)
+		return true
+	default:
+	}
+
+	if p.overflow != OverflowBlock {
+		p.dropped.Add(1)
+		return false
+	}
+
+	p.blocked.Add(1)
+	select {
+	case p.queue <- click:
+		p.enqueued.Add(1)
+		return true
+	case <-ctx.Done():
+		p.dropped.Add(1)
+		return false
+	}
+}
+
+func (p *ClickPool) Stats() ClickPoolStats {
+	return ClickPoolStats{
+		Enqueued: p.enqueued.Load(),
+		Dropped:  p.dropped.Load(),
+		Blocked:  p.blocked.Load(),
+		Written:  p.written.Load(),
+		Failed:   p.failed.Load(),
+	}
+}
+
+func (p *ClickPool) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.Click, 0, p.batchSize)
+	for {
+		select {
+		case click, ok := <-p.queue:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+			batch = append(batch, click)
+			if len(batch) >= p.batchSize {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			p.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (p *ClickPool) flush(batch []model.Click) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clickFlushTimeout)
+	defer cancel()
+
+	if err := p.clickRepository.CreateBatch(ctx, batch); err != nil {
+		p.failed.Add(int64(len(batch)))
+		p.logger.ErrorContext(ctx, "failed to write clicks",
+			slog.Int("count", len(batch)),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	p.written.Add(int64(len(batch)))
+}
@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	minRebuildDelay = time.Second
+	maxRebuildDelay = time.Minute
+)
+
+// FilterBuilder (re)builds the short code filter from Postgres at startup,
+// whenever it goes stale and every interval. The periodic rebuild picks up
+// codes created elsewhere whose announcement was lost, which the filter
+// would otherwise keep reporting as missing. A failed build is retried
+// with backoff; until the first one succeeds lookups bypass the filter.
+type FilterBuilder struct {
+	interval        time.Duration
+	logger          *slog.Logger
+	shortCodeFilter ShortCodeFilter
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewFilterBuilder(
+	interval time.Duration,
+	logger *slog.Logger,
+	shortCodeFilter ShortCodeFilter,
+) *FilterBuilder {
+	return &FilterBuilder{
+		interval:        interval,
+		logger:          logger,
+		shortCodeFilter: shortCodeFilter,
+	}
+}
+
+func (f *FilterBuilder) Start() {
+	if !f.shortCodeFilter.Enabled() {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.done = make(chan struct{})
+
+	go f.run(ctx)
+}
+
+func (f *FilterBuilder) Stop() {
+	if f.cancel == nil {
+		return
+	}
+	f.cancel()
+	<-f.done
+}
+
+func (f *FilterBuilder) run(ctx context.Context) {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.shortCodeFilter.Stale():
+		case <-ticker.C:
+		}
+
+		if !f.rebuild(ctx) {
+			return
+		}
+		ticker.Reset(f.interval)
+	}
+}
+
+// rebuild retries until the filter is built, it returns false when ctx is
+// done first.
+func (f *FilterBuilder) rebuild(ctx context.Context) bool {
+	delay := minRebuildDelay
+	for {
+		start := time.Now()
+		count, err := f.shortCodeFilter.Rebuild(ctx)
+		if err == nil {
+			f.logger.InfoContext(ctx, "rebuilt short code filter",
+				slog.Int("codes", count),
+				slog.Duration("took", time.Since(start)),
+			)
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		f.logger.WarnContext(ctx, "failed to rebuild short code filter",
+			slog.Duration("retry_in", delay),
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRebuildDelay)
+	}
+}
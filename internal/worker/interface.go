@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"time"
+	"url_shortener/internal/model"
+)
+
+type URLRepository interface {
+	DeleteExpired(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type PopularURLRepository interface {
+	ListPopular(ctx context.Context, since time.Time, limit int) ([]model.URL, error)
+	List(ctx context.Context, filter model.URLFilter) ([]model.URL, error)
+	ListByShortCodes(ctx context.Context, shortCodes []string) ([]model.URL, error)
+}
+
+type URLWarmer interface {
+	Warm(ctx context.Context, urls []model.URL) (int, error)
+}
+
+type ClickRepository interface {
+	CreateBatch(ctx context.Context, clicks []model.Click) error
+}
+
+type BlocklistRepository interface {
+	Reload() (bool, error)
+	Len() int
+}
+
+type InvalidationRepository interface {
+	Subscribe(ctx context.Context, onSubscribed func(), onInvalidate func(shortCode string)) error
+}
+
+type URLCache interface {
+	Invalidate(shortCode string)
+	Purge()
+}
+
+type ShortCodeFilter interface {
+	Enabled() bool
+	Stale() <-chan struct{}
+	Rebuild(ctx context.Context) (int, error)
+}
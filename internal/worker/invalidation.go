@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	minResubscribeDelay = time.Second
+	maxResubscribeDelay = 30 * time.Second
+)
+
+// CacheInvalidator passes the links other instances created or changed to
+// the in-process caches. Whenever the subscription is (re)established the
+// caches are purged, since invalidations sent while it was down are lost;
+// a failed subscription is retried with backoff.
+type CacheInvalidator struct {
+	logger                 *slog.Logger
+	invalidationRepository InvalidationRepository
+	urlCaches              []URLCache
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewCacheInvalidator(
+	logger *slog.Logger,
+	invalidationRepository InvalidationRepository,
+	urlCaches []URLCache,
+) *CacheInvalidator {
+	return &CacheInvalidator{
+		logger:                 logger,
+		invalidationRepository: invalidationRepository,
+		urlCaches:              urlCaches,
+	}
+}
+
+func (c *CacheInvalidator) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+
+	go c.run(ctx)
+}
+
+func (c *CacheInvalidator) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	<-c.done
+}
+
+func (c *CacheInvalidator) run(ctx context.Context) {
+	defer close(c.done)
+
+	delay := minResubscribeDelay
+	for {
+		var subscribed atomic.Bool
+		err := c.invalidationRepository.Subscribe(ctx,
+			func() {
+				subscribed.Store(true)
+				c.purge()
+			},
+			c.invalidate,
+		)
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.purge()
+		if subscribed.Load() {
+			delay = minResubscribeDelay
+		}
+		c.logger.WarnContext(ctx, "cache invalidation subscription lost",
+			slog.Duration("retry_in", delay),
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxResubscribeDelay)
+	}
+}
+
+func (c *CacheInvalidator) invalidate(shortCode string) {
+	for _, urlCache := range c.urlCaches {
+		urlCache.Invalidate(shortCode)
+	}
+}
+
+func (c *CacheInvalidator) purge() {
+	for _, urlCache := range c.urlCaches {
+		urlCache.Purge()
+	}
+}
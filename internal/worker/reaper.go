@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Reaper periodically purges links that expired more than gracePeriod ago.
+// Until then expired links keep answering 410 instead of 404.
+type Reaper struct {
+	interval      time.Duration
+	gracePeriod   time.Duration
+	batchSize     int
+	logger        *slog.Logger
+	urlRepository URLRepository
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewReaper(
+	interval time.Duration,
+	gracePeriod time.Duration,
+	batchSize int,
+	logger *slog.Logger,
+	urlRepository URLRepository,
+) *Reaper {
+	return &Reaper{
+		interval:      interval,
+		gracePeriod:   gracePeriod,
+		batchSize:     batchSize,
+		logger:        logger,
+		urlRepository: urlRepository,
+	}
+}
+
+func (r *Reaper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+
+	go r.run(ctx)
+}
+
+func (r *Reaper) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+func (r *Reaper) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := r.reap(ctx)
+			if err != nil {
+				r.logger.ErrorContext(ctx, "failed to reap expired urls",
+					slog.Int("deleted", count),
+					slog.Any("error", err),
+				)
+				continue
+			}
+			if count > 0 {
+				r.logger.InfoContext(ctx, "reaped expired urls",
+					slog.Int("deleted", count),
+				)
+			}
+		}
+	}
+}
+
+func (r *Reaper) reap(ctx context.Context) (int, error) {
+	const op = "worker.Reaper.reap"
+
+	before := time.Now().Add(-r.gracePeriod)
+
+	total := 0
+	for ctx.Err() == nil {
+		count, err := r.urlRepository.DeleteExpired(ctx, before, r.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", op, err)
+		}
+		total += count
+		if count < r.batchSize {
+			break
+		}
+	}
+
+	return total, nil
+}
@@ -0,0 +1,143 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+	"url_shortener/internal/model"
+)
+
+const (
+	minWarmDelay = time.Second
+	maxWarmDelay = time.Minute
+	// warmBatchSize is how many links are re-read and cached at a time.
+	warmBatchSize = 500
+)
+
+// CacheWarmer loads the links most likely to be requested into the cache
+// at startup, so a deploy or a flushed cache doesn't send the first wave
+// of redirects to Postgres. It picks the size links clicked most within
+// window, or the newest ones when by is "recent", and retries with backoff
+// while Postgres or the cache is unavailable. A size of zero disables it.
+type CacheWarmer struct {
+	size                 int
+	by                   string
+	window               time.Duration
+	logger               *slog.Logger
+	popularURLRepository PopularURLRepository
+	urlWarmer            URLWarmer
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewCacheWarmer(
+	size int,
+	by string,
+	window time.Duration,
+	logger *slog.Logger,
+	popularURLRepository PopularURLRepository,
+	urlWarmer URLWarmer,
+) *CacheWarmer {
+	return &CacheWarmer{
+		size:                 size,
+		by:                   by,
+		window:               window,
+		logger:               logger,
+		popularURLRepository: popularURLRepository,
+		urlWarmer:            urlWarmer,
+	}
+}
+
+func (c *CacheWarmer) Start() {
+	if c.size <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+
+	go c.run(ctx)
+}
+
+func (c *CacheWarmer) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	<-c.done
+}
+
+func (c *CacheWarmer) run(ctx context.Context) {
+	defer close(c.done)
+
+	delay := minWarmDelay
+	for {
+		start := time.Now()
+		warmed, err := c.warm(ctx)
+		if err == nil {
+			c.logger.InfoContext(ctx, "warmed url cache",
+				slog.String("by", c.by),
+				slog.Int("urls", warmed),
+				slog.Duration("took", time.Since(start)),
+			)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.logger.WarnContext(ctx, "failed to warm url cache",
+			slog.Int("warmed", warmed),
+			slog.Duration("retry_in", delay),
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxWarmDelay)
+	}
+}
+
+func (c *CacheWarmer) warm(ctx context.Context) (int, error) {
+	var (
+		urls []model.URL
+		err  error
+	)
+	if c.by == "recent" {
+		urls, err = c.popularURLRepository.List(ctx, model.URLFilter{Limit: c.size})
+	} else {
+		urls, err = c.popularURLRepository.ListPopular(ctx, time.Now().Add(-c.window), c.size)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	warmed := 0
+	for batch := range slices.Chunk(urls, warmBatchSize) {
+		shortCodes := make([]string, len(batch))
+		for i := range batch {
+			shortCodes[i] = batch[i].ShortCode
+		}
+
+		// The list may be minutes old by now, a link changed or deleted
+		// since must not be cached as it was.
+		fresh, err := c.popularURLRepository.ListByShortCodes(ctx, shortCodes)
+		if err != nil {
+			return warmed, err
+		}
+
+		n, err := c.urlWarmer.Warm(ctx, fresh)
+		warmed += n
+		if err != nil {
+			return warmed, err
+		}
+	}
+
+	return warmed, nil
+}
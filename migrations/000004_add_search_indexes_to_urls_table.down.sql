@@ -0,0 +1,3 @@
+drop index if exists urls_original_url_trgm_idx;
+
+drop index if exists urls_created_at_idx;
@@ -0,0 +1,5 @@
+create extension if not exists pg_trgm;
+
+create index if not exists urls_created_at_idx on urls (created_at);
+
+create index if not exists urls_original_url_trgm_idx on urls using gin (original_url gin_trgm_ops);
@@ -0,0 +1,11 @@
+create table if not exists clicks(
+    id bigserial primary key,
+    url_id integer not null references urls (id) on delete cascade,
+    clicked_at timestamptz not null,
+    referrer text not null default '',
+    user_agent text not null default '',
+    ip varchar(45) not null default '',
+    accept_language text not null default ''
+);
+
+create index if not exists clicks_url_id_clicked_at_idx on clicks (url_id, clicked_at);
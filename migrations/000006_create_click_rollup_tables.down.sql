@@ -0,0 +1,10 @@
+drop table if exists click_visitors;
+
+drop table if exists click_dimensions;
+
+drop table if exists click_series;
+
+alter table clicks
+    drop column if exists os,
+    drop column if exists browser,
+    drop column if exists country;
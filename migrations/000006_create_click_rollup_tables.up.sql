@@ -0,0 +1,29 @@
+alter table clicks
+    add column if not exists country varchar(16) not null default '',
+    add column if not exists browser varchar(64) not null default '',
+    add column if not exists os varchar(64) not null default '';
+
+create table if not exists click_series(
+    url_id integer not null references urls (id) on delete cascade,
+    granularity varchar(8) not null,
+    bucket_start timestamptz not null,
+    clicks bigint not null default 0,
+    primary key (url_id, granularity, bucket_start)
+);
+
+create table if not exists click_dimensions(
+    url_id integer not null references urls (id) on delete cascade,
+    day date not null,
+    dimension varchar(16) not null,
+    value text not null,
+    clicks bigint not null default 0,
+    primary key (url_id, dimension, day, value)
+);
+
+-- sketch is a HyperLogLog of the day's visitors, see internal/utils/hll.
+create table if not exists click_visitors(
+    url_id integer not null references urls (id) on delete cascade,
+    day date not null,
+    sketch bytea not null,
+    primary key (url_id, day)
+);
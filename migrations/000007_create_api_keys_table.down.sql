@@ -0,0 +1,5 @@
+drop index if exists urls_owner_id_idx;
+
+alter table urls drop column if exists owner_id;
+
+drop table if exists api_keys;
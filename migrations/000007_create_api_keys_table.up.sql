@@ -0,0 +1,15 @@
+create table if not exists api_keys(
+    id serial primary key,
+    owner_id uuid not null,
+    name varchar(255) not null,
+    prefix varchar(16) not null,
+    key_hash char(64) unique not null,
+    created_at timestamp default now(),
+    revoked_at timestamptz
+);
+
+create index if not exists api_keys_owner_id_idx on api_keys (owner_id);
+
+alter table urls add column if not exists owner_id uuid;
+
+create index if not exists urls_owner_id_idx on urls (owner_id, id);
@@ -0,0 +1,5 @@
+drop sequence if exists short_code_counter;
+
+drop index if exists urls_counter_idx;
+
+alter table urls drop column if exists counter;
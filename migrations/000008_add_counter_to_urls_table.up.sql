@@ -0,0 +1,5 @@
+alter table urls add column if not exists counter bigint;
+
+create index if not exists urls_counter_idx on urls (counter) where counter is not null;
+
+create sequence if not exists short_code_counter;
@@ -0,0 +1,3 @@
+drop index if exists urls_original_url_hash_idx;
+
+alter table urls drop column if exists original_url_hash;
@@ -0,0 +1,6 @@
+alter table urls add column if not exists original_url_hash char(64);
+
+update urls set original_url_hash = encode(sha256(convert_to(original_url, 'UTF8')), 'hex')
+where original_url_hash is null;
+
+create index if not exists urls_original_url_hash_idx on urls (original_url_hash, owner_id);
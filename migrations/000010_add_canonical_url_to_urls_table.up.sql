@@ -0,0 +1,5 @@
+alter table urls add column if not exists canonical_url text;
+
+update urls set canonical_url = original_url where canonical_url is null;
+
+alter table urls alter column canonical_url set not null;